@@ -0,0 +1,196 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2013-2017 Oryx(ossrs)
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package avc
+
+import (
+	"bytes"
+
+	"github.com/ossrs/go-oryx-lib/errors"
+)
+
+// EscapeEmulationPrevention inserts the emulation-prevention byte 0x03 after
+// every 0x0000 sequence in the RBSP payload that is followed by a byte in
+// 0x00..0x03, so that it never contains a byte-aligned start-code prefix. @doc ISO_IEC_14496-10-AVC-2003.pdf at page
+// 41, 7.3.1 NAL unit syntax, emulation_prevention_three_byte.
+func EscapeEmulationPrevention(rbsp []byte) []byte {
+	var r []byte
+	zeros := 0
+	for _, b := range rbsp {
+		if zeros >= 2 && b <= 0x03 {
+			r = append(r, 0x03)
+			zeros = 0
+		}
+		r = append(r, b)
+		if b == 0x00 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return r
+}
+
+// UnescapeEmulationPrevention removes the emulation-prevention byte 0x03
+// previously inserted by EscapeEmulationPrevention, recovering the original
+// RBSP payload.
+func UnescapeEmulationPrevention(ebsp []byte) []byte {
+	var r []byte
+	zeros := 0
+	for i := 0; i < len(ebsp); i++ {
+		b := ebsp[i]
+		if zeros >= 2 && b == 0x03 && i+1 < len(ebsp) && ebsp[i+1] <= 0x03 {
+			zeros = 0
+			continue
+		}
+		r = append(r, b)
+		if b == 0x00 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return r
+}
+
+// startCode is the 3-byte Annex B start-code prefix, optionally preceded by
+// an extra leading zero byte to form the 4-byte variant.
+var startCode = []byte{0x00, 0x00, 0x01}
+
+// AnnexBNALUHandler receives each NALU found in an Annex B byte stream.
+type AnnexBNALUHandler func(nalu *NALU) error
+
+// AnnexBReader scans an Annex B byte stream, as used by MPEG-TS/HLS, for
+// NALUs delimited by 3-byte(0x000001) or 4-byte(0x00000001) start codes.
+// @doc ISO_IEC_14496-10-AVC-2003.pdf at page 211, Annex B Byte stream Format.
+type AnnexBReader struct {
+	// The handler invoked for each complete NALU found in the stream.
+	OnNALU AnnexBNALUHandler
+	// The buffered bytes not yet consumed, including an in-progress NALU.
+	buf []byte
+	// Whether Close has already flushed the last pending NALU.
+	closed bool
+}
+
+// NewAnnexBReader creates an AnnexBReader which pushes any NALUs found in
+// Write calls to onNALU.
+func NewAnnexBReader(onNALU AnnexBNALUHandler) *AnnexBReader {
+	return &AnnexBReader{OnNALU: onNALU}
+}
+
+// Write pushes an arbitrary chunk of Annex B bytes, as they would arrive from
+// a TS demuxer, and feeds each complete NALU to OnNALU as soon as it is
+// found. A trailing partial NALU is buffered until more bytes, or Close,
+// arrive.
+func (v *AnnexBReader) Write(p []byte) (n int, err error) {
+	v.buf = append(v.buf, p...)
+
+	for {
+		start := bytes.Index(v.buf, startCode)
+		if start < 0 {
+			break
+		}
+
+		next := bytes.Index(v.buf[start+len(startCode):], startCode)
+		if next < 0 {
+			// Only one start code buffered so far, wait for the next one (or
+			// Close) to know where this NALU ends.
+			break
+		}
+		next += start + len(startCode)
+
+		if err := v.emit(v.buf[start+len(startCode) : next]); err != nil {
+			return len(p), errors.WithMessage(err, "emit")
+		}
+
+		v.buf = v.buf[next:]
+	}
+
+	return len(p), nil
+}
+
+// Close flushes the last buffered NALU, if any, to OnNALU.
+func (v *AnnexBReader) Close() error {
+	if v.closed {
+		return nil
+	}
+	v.closed = true
+
+	start := bytes.Index(v.buf, startCode)
+	if start < 0 {
+		return nil
+	}
+
+	if err := v.emit(v.buf[start+len(startCode):]); err != nil {
+		return errors.WithMessage(err, "emit")
+	}
+	v.buf = nil
+	return nil
+}
+
+// emit strips every trailing zero byte (such as the extra leading zero of a
+// following 4-byte start code) and dispatches the NALU.
+func (v *AnnexBReader) emit(b []byte) error {
+	for len(b) > 0 && b[len(b)-1] == 0x00 {
+		b = b[:len(b)-1]
+	}
+	if len(b) == 0 {
+		return nil
+	}
+
+	nalu := newNALU()
+	if err := nalu.UnmarshalBinary(b); err != nil {
+		return errors.WithMessage(err, "unmarshal nalu")
+	}
+	return v.OnNALU(nalu)
+}
+
+// MarshalAnnexB writes nalus as an Annex B byte stream, prefixing each one
+// with a 4-byte start code.
+func MarshalAnnexB(nalus ...*NALU) ([]byte, error) {
+	var r []byte
+	for _, nalu := range nalus {
+		b, err := nalu.MarshalBinary()
+		if err != nil {
+			return nil, errors.WithMessage(err, "marshal nalu")
+		}
+		r = append(r, 0x00, 0x00, 0x00, 0x01)
+		r = append(r, b...)
+	}
+	return r, nil
+}
+
+// UnmarshalAnnexB parses a complete, in-memory Annex B byte stream into its
+// NALUs. For incrementally arriving bytes, use AnnexBReader instead.
+func UnmarshalAnnexB(data []byte) ([]*NALU, error) {
+	var nalus []*NALU
+	r := NewAnnexBReader(func(nalu *NALU) error {
+		nalus = append(nalus, nalu)
+		return nil
+	})
+	if _, err := r.Write(data); err != nil {
+		return nil, errors.WithMessage(err, "write")
+	}
+	if err := r.Close(); err != nil {
+		return nil, errors.WithMessage(err, "close")
+	}
+	return nalus, nil
+}
@@ -0,0 +1,134 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2013-2017 Oryx(ossrs)
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package avc
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestEscapeUnescapeEmulationPrevention(t *testing.T) {
+	cases := [][]byte{
+		{0x00, 0x00, 0x00},
+		{0x00, 0x00, 0x01},
+		{0x00, 0x00, 0x02},
+		{0x00, 0x00, 0x03},
+		{0x01, 0x00, 0x00, 0x00, 0x01, 0x02},
+		{0x00, 0x00, 0x00, 0x00, 0x01},
+		{},
+	}
+
+	for _, c := range cases {
+		escaped := EscapeEmulationPrevention(c)
+		if bytes.Contains(escaped, []byte{0x00, 0x00, 0x00}) ||
+			bytes.Contains(escaped, []byte{0x00, 0x00, 0x01}) ||
+			bytes.Contains(escaped, []byte{0x00, 0x00, 0x02}) {
+			t.Errorf("EscapeEmulationPrevention(%v) = %v still contains a start-code-like run", c, escaped)
+		}
+
+		got := UnescapeEmulationPrevention(escaped)
+		if !reflect.DeepEqual(got, c) && !(len(got) == 0 && len(c) == 0) {
+			t.Errorf("UnescapeEmulationPrevention(Escape(%v)) = %v, want %v", c, got, c)
+		}
+	}
+}
+
+func TestMarshalUnmarshalAnnexB(t *testing.T) {
+	sps := newNALU()
+	sps.nalRefIDC = 3
+	sps.naluType = NALUTypeSPS
+	sps.data = []byte{0x01, 0x02, 0x00, 0x00, 0x03}
+
+	idr := newNALU()
+	idr.nalRefIDC = 3
+	idr.naluType = NALUTypeIDR
+	idr.data = []byte{0xaa, 0xbb, 0xcc}
+
+	b, err := MarshalAnnexB(sps, idr)
+	if err != nil {
+		t.Fatalf("MarshalAnnexB returned error: %v", err)
+	}
+
+	nalus, err := UnmarshalAnnexB(b)
+	if err != nil {
+		t.Fatalf("UnmarshalAnnexB returned error: %v", err)
+	}
+	if len(nalus) != 2 {
+		t.Fatalf("UnmarshalAnnexB returned %v NALUs, want 2", len(nalus))
+	}
+	if nalus[0].NALUType() != NALUTypeSPS || !bytes.Equal(nalus[0].Data(), sps.data) {
+		t.Errorf("nalus[0] = %v %v, want SPS %v", nalus[0].NALUType(), nalus[0].Data(), sps.data)
+	}
+	if nalus[1].NALUType() != NALUTypeIDR || !bytes.Equal(nalus[1].Data(), idr.data) {
+		t.Errorf("nalus[1] = %v %v, want IDR %v", nalus[1].NALUType(), nalus[1].Data(), idr.data)
+	}
+}
+
+func TestAnnexBReader_StreamedAcrossWrites(t *testing.T) {
+	sps := newNALU()
+	sps.nalRefIDC = 3
+	sps.naluType = NALUTypeSPS
+	sps.data = []byte{0x01, 0x02, 0x03}
+
+	idr := newNALU()
+	idr.nalRefIDC = 3
+	idr.naluType = NALUTypeIDR
+	idr.data = []byte{0xaa, 0xbb}
+
+	b, err := MarshalAnnexB(sps, idr)
+	if err != nil {
+		t.Fatalf("MarshalAnnexB returned error: %v", err)
+	}
+
+	var got []*NALU
+	r := NewAnnexBReader(func(nalu *NALU) error {
+		got = append(got, nalu)
+		return nil
+	})
+
+	// Feed the Annex B stream one byte at a time, simulating bytes trickling
+	// in from a TS demuxer.
+	for i := range b {
+		if _, err := r.Write(b[i : i+1]); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("got %v NALUs, want 2", len(got))
+	}
+	if got[0].NALUType() != NALUTypeSPS || !bytes.Equal(got[0].Data(), sps.data) {
+		t.Errorf("got[0] = %v %v, want SPS %v", got[0].NALUType(), got[0].Data(), sps.data)
+	}
+	if got[1].NALUType() != NALUTypeIDR || !bytes.Equal(got[1].Data(), idr.data) {
+		t.Errorf("got[1] = %v %v, want IDR %v", got[1].NALUType(), got[1].Data(), idr.data)
+	}
+
+	// Close is idempotent.
+	if err := r.Close(); err != nil {
+		t.Fatalf("second Close returned error: %v", err)
+	}
+}
@@ -32,6 +32,8 @@
 package avc
 
 import (
+	"fmt"
+
 	"github.com/ossrs/go-oryx-lib/errors"
 )
 
@@ -41,6 +43,97 @@ type NALRefIDC uint8
 // @doc ISO_IEC_14496-10-AVC-2003.pdf at page 44, 7.3.1 NAL unit syntax
 type NALUType uint8
 
+// @doc ISO_IEC_14496-10-AVC-2003.pdf at page 63, Table 7-1 NAL unit type
+// codes.
+const (
+	NALUTypeNonIDR         NALUType = 1
+	NALUTypeDataPartitionA NALUType = 2
+	NALUTypeDataPartitionB NALUType = 3
+	NALUTypeDataPartitionC NALUType = 4
+	NALUTypeIDR            NALUType = 5
+	NALUTypeSEI            NALUType = 6
+	NALUTypeSPS            NALUType = 7
+	NALUTypePPS            NALUType = 8
+	NALUTypeAUD            NALUType = 9
+	NALUTypeEndOfSequence  NALUType = 10
+	NALUTypeEndOfStream    NALUType = 11
+	NALUTypeFillerData     NALUType = 12
+	NALUTypeSPSExt         NALUType = 13
+	NALUTypePrefix         NALUType = 14
+	NALUTypeSubsetSPS      NALUType = 15
+	// Annex H, multiview/3D AVC auxiliary coded picture without partitioning.
+	NALUTypeAuxSliceWithoutPartitioning NALUType = 19
+	NALUTypeSliceExt                    NALUType = 20
+)
+
+func (v NALUType) String() string {
+	switch v {
+	case NALUTypeNonIDR:
+		return "NonIDR"
+	case NALUTypeDataPartitionA:
+		return "DataPartitionA"
+	case NALUTypeDataPartitionB:
+		return "DataPartitionB"
+	case NALUTypeDataPartitionC:
+		return "DataPartitionC"
+	case NALUTypeIDR:
+		return "IDR"
+	case NALUTypeSEI:
+		return "SEI"
+	case NALUTypeSPS:
+		return "SPS"
+	case NALUTypePPS:
+		return "PPS"
+	case NALUTypeAUD:
+		return "AUD"
+	case NALUTypeEndOfSequence:
+		return "EndOfSequence"
+	case NALUTypeEndOfStream:
+		return "EndOfStream"
+	case NALUTypeFillerData:
+		return "FillerData"
+	case NALUTypeSPSExt:
+		return "SPSExt"
+	case NALUTypePrefix:
+		return "Prefix"
+	case NALUTypeSubsetSPS:
+		return "SubsetSPS"
+	case NALUTypeAuxSliceWithoutPartitioning:
+		return "AuxSliceWithoutPartitioning"
+	case NALUTypeSliceExt:
+		return "SliceExt"
+	default:
+		return fmt.Sprintf("NALUType(%v)", uint8(v))
+	}
+}
+
+// IsVCL reports whether the NALU type carries coded slice data (a Video
+// Coding Layer unit).
+func (v NALUType) IsVCL() bool {
+	switch v {
+	case NALUTypeNonIDR, NALUTypeDataPartitionA, NALUTypeDataPartitionB, NALUTypeDataPartitionC,
+		NALUTypeIDR, NALUTypeAuxSliceWithoutPartitioning, NALUTypeSliceExt:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsParameterSet reports whether the NALU type carries a parameter set.
+func (v NALUType) IsParameterSet() bool {
+	switch v {
+	case NALUTypeSPS, NALUTypePPS, NALUTypeSPSExt, NALUTypeSubsetSPS:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsKeyframe reports whether the NALU type is an IDR slice.
+func (v NALUType) IsKeyframe() bool {
+	return v == NALUTypeIDR
+}
+
 // @doc ISO_IEC_14496-10-AVC-2003.pdf at page 60, 7.4.1 NAL unit semantics
 type NALUHeader struct {
 	// The 2-bits nal_ref_idc.
@@ -63,6 +156,13 @@ func newNALU() *NALU {
 	return &NALU{NALUHeader: newNALUHeader()}
 }
 
+// NewNALU creates an empty NALU, to be filled in by UnmarshalBinary. This is
+// the entry point for other packages, such as avc/rtp, that need to build a
+// NALU from raw bytes they received over the wire.
+func NewNALU() *NALU {
+	return newNALU()
+}
+
 func (v *NALU) UnmarshalBinary(data []byte) error {
 	if len(data) < 1 {
 		return errors.New("empty NALU")
@@ -83,6 +183,21 @@ func (v *NALU) MarshalBinary() ([]byte, error) {
 	return append(h, v.data...), nil
 }
 
+// NALRefIDC returns the 2-bit nal_ref_idc.
+func (v *NALU) NALRefIDC() NALRefIDC {
+	return v.nalRefIDC
+}
+
+// NALUType returns the 5-bit nal_unit_type.
+func (v *NALU) NALUType() NALUType {
+	return v.naluType
+}
+
+// Data returns the NALU payload, excluding the 1-byte NALU header.
+func (v *NALU) Data() []byte {
+	return v.data
+}
+
 // @doc Annex A Profiles and levels, ISO_IEC_14496-10-AVC-2003.pdf, page 205.
 // @note that it's uint8 in IBMF, but extended in other specs, so we use uint16.
 type AVCProfile uint16
@@ -198,10 +313,12 @@ type AVCDecoderConfigurationRecord struct {
 	// ISO/IEC 14496-10.
 	// @remark It's 8 bits.
 	avcProfileIndication AVCProfile
+	// It is the constraint_set flags byte that occurs between profile_idc and
+	// level_idc in the SPS: constraint_set0_flag..constraint_set5_flag plus 2
+	// reserved bits.
+	// @remark Previously misnamed profileCompatibility.
+	constraintSetFlags uint8
 	// It contains the level code as defined in ISO/IEC 14496-10.
-	profileCompatibility uint8
-	// It indicates the length in bytes of the NALUnitLength field in an AVC video sample
-	// or AVC parameter set sample of the associated stream minus one.
 	avcLevelIndication AVCLevel
 	// It indicates the length in bytes of the NALUnitLength field in an AVC video sample
 	// or AVC parameter set sample of the associated stream minus one.
@@ -212,11 +329,89 @@ type AVCDecoderConfigurationRecord struct {
 	// It contains a PPS NAL unit, as specified in ISO/IEC 14496-10. PPSs shall occur in
 	// order of ascending parameter set identifier with gaps being allowed.
 	pictureParameterSetNALUnits []*NALU
-	// @remark We ignore the sequenceParameterSetExtNALUnit.
+
+	// The following fields are present only for the profiles accepted by
+	// hasChromaFormatIDC, that is High/High10/High422/High444 and the other
+	// profiles whose SPS carries chroma_format_idc, @doc ISO_IEC_14496-15-AVC-format-2012.pdf at page 16,
+	// 5.2.4.1.1 Syntax (as amended for those profiles).
+	chromaFormat                    uint8
+	bitDepthLumaMinus8              uint8
+	bitDepthChromaMinus8            uint8
+	sequenceParameterSetExtNALUnits []*NALU
 }
 
 func NewAVCDecoderConfigurationRecord() *AVCDecoderConfigurationRecord {
-	return &AVCDecoderConfigurationRecord{}
+	return &AVCDecoderConfigurationRecord{configurationVersion: 1}
+}
+
+// NewAVCDecoderConfigurationRecordFromNALUs builds a record from an elementary
+// stream's SPS and PPS NALUs, deriving avcProfileIndication/constraintSetFlags/
+// avcLevelIndication and, for the High profiles, the chroma/bit-depth fields
+// straight from the first SPS, so a caller can MarshalBinary it into an
+// AVCC/avcC box.
+func NewAVCDecoderConfigurationRecordFromNALUs(
+	spsNALUs, ppsNALUs []*NALU, lengthSizeMinusOne uint8,
+) (*AVCDecoderConfigurationRecord, error) {
+	if len(spsNALUs) == 0 {
+		return nil, errors.New("requires at least one sps")
+	}
+
+	spsBytes, err := spsNALUs[0].MarshalBinary()
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal sps")
+	}
+
+	sps := newSeqParameterSet()
+	if err := sps.UnmarshalBinary(spsBytes); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal sps")
+	}
+
+	v := NewAVCDecoderConfigurationRecord()
+	v.avcProfileIndication = sps.ProfileIDC
+	v.constraintSetFlags = sps.ConstraintSetFlags
+	v.avcLevelIndication = sps.LevelIDC
+	v.lengthSizeMinusOne = lengthSizeMinusOne & 0x03
+	v.sequenceParameterSetNALUnits = spsNALUs
+	v.pictureParameterSetNALUnits = ppsNALUs
+
+	if hasChromaFormatIDC(sps.ProfileIDC) {
+		v.chromaFormat = uint8(sps.ChromaFormatIDC)
+		v.bitDepthLumaMinus8 = uint8(sps.BitDepthLumaMinus8)
+		v.bitDepthChromaMinus8 = uint8(sps.BitDepthChromaMinus8)
+	}
+
+	return v, nil
+}
+
+// ProfileIndication returns avcProfileIndication.
+func (v *AVCDecoderConfigurationRecord) ProfileIndication() AVCProfile {
+	return v.avcProfileIndication
+}
+
+// ConstraintSetFlags returns the constraint_set0_flag..constraint_set5_flag
+// byte.
+func (v *AVCDecoderConfigurationRecord) ConstraintSetFlags() uint8 {
+	return v.constraintSetFlags
+}
+
+// LevelIndication returns avcLevelIndication.
+func (v *AVCDecoderConfigurationRecord) LevelIndication() AVCLevel {
+	return v.avcLevelIndication
+}
+
+// LengthSizeMinusOne returns the NALUnitLength field size in bytes, minus one.
+func (v *AVCDecoderConfigurationRecord) LengthSizeMinusOne() uint8 {
+	return v.lengthSizeMinusOne
+}
+
+// SequenceParameterSets returns the record's SPS NALUs.
+func (v *AVCDecoderConfigurationRecord) SequenceParameterSets() []*NALU {
+	return v.sequenceParameterSetNALUnits
+}
+
+// PictureParameterSets returns the record's PPS NALUs.
+func (v *AVCDecoderConfigurationRecord) PictureParameterSets() []*NALU {
+	return v.pictureParameterSetNALUnits
 }
 
 func (v *AVCDecoderConfigurationRecord) UnmarshalBinary(data []byte) error {
@@ -227,14 +422,14 @@ func (v *AVCDecoderConfigurationRecord) UnmarshalBinary(data []byte) error {
 
 	v.configurationVersion = uint8(b[0])
 	v.avcProfileIndication = AVCProfile(uint8(b[1]))
-	v.profileCompatibility = uint8(b[2])
+	v.constraintSetFlags = uint8(b[2])
 	v.avcLevelIndication = AVCLevel(uint8(b[3]))
 	v.lengthSizeMinusOne = uint8(b[4]) & 0x03
-	b = b[4:]
+	b = b[5:]
 
 	numOfSequenceParameterSets := uint8(b[0]) & 0x1f
 	b = b[1:]
-	for i := 0; i < numOfSequenceParameterSets; i++ {
+	for i := uint8(0); i < numOfSequenceParameterSets; i++ {
 		if len(b) < 2 {
 			return errors.Errorf("requires 2+ only %v bytes", len(b))
 		}
@@ -258,7 +453,7 @@ func (v *AVCDecoderConfigurationRecord) UnmarshalBinary(data []byte) error {
 	}
 	numOfPictureParameterSets := uint8(b[0])
 	b = b[1:]
-	for i := 0; i < numOfPictureParameterSets; i++ {
+	for i := uint8(0); i < numOfPictureParameterSets; i++ {
 		if len(b) < 2 {
 			return errors.Errorf("requiers 2+ only %v bytes", len(b))
 		}
@@ -277,5 +472,113 @@ func (v *AVCDecoderConfigurationRecord) UnmarshalBinary(data []byte) error {
 
 		v.pictureParameterSetNALUnits = append(v.pictureParameterSetNALUnits, pps)
 	}
+
+	if !hasChromaFormatIDC(v.avcProfileIndication) {
+		return nil
+	}
+
+	if len(b) < 4 {
+		return errors.Errorf("requires 4+ only %v bytes for high profile ext", len(b))
+	}
+	v.chromaFormat = uint8(b[0]) & 0x03
+	v.bitDepthLumaMinus8 = uint8(b[1]) & 0x07
+	v.bitDepthChromaMinus8 = uint8(b[2]) & 0x07
+	numOfSequenceParameterSetExt := uint8(b[3])
+	b = b[4:]
+
+	for i := uint8(0); i < numOfSequenceParameterSetExt; i++ {
+		if len(b) < 2 {
+			return errors.Errorf("requires 2+ only %v bytes", len(b))
+		}
+		sequenceParameterSetExtLength := int(uint16(b[0])<<8 | uint16(b[1]))
+		b = b[2:]
+
+		if len(b) < sequenceParameterSetExtLength {
+			return errors.Errorf("requires %v only %v bytes", sequenceParameterSetExtLength, len(b))
+		}
+		spsExt := newNALU()
+		if err := spsExt.UnmarshalBinary(b[:sequenceParameterSetExtLength]); err != nil {
+			return errors.WithMessage(err, "unmarshal")
+		}
+		b = b[sequenceParameterSetExtLength:]
+
+		v.sequenceParameterSetExtNALUnits = append(v.sequenceParameterSetExtNALUnits, spsExt)
+	}
+
 	return nil
 }
+
+// MarshalBinary emits the AVCC/avcC box payload, the inverse of
+// UnmarshalBinary, so a caller can synthesize a decoder configuration record
+// from an Annex B elementary stream's SPS/PPS NALUs.
+func (v *AVCDecoderConfigurationRecord) MarshalBinary() ([]byte, error) {
+	if len(v.sequenceParameterSetNALUnits) > 0x1f {
+		return nil, errors.Errorf("numOfSequenceParameterSets=%v overflows 5 bits", len(v.sequenceParameterSetNALUnits))
+	}
+	if len(v.pictureParameterSetNALUnits) > 0xff {
+		return nil, errors.Errorf("numOfPictureParameterSets=%v overflows uint8", len(v.pictureParameterSetNALUnits))
+	}
+
+	b := []byte{
+		v.configurationVersion,
+		byte(v.avcProfileIndication),
+		v.constraintSetFlags,
+		byte(v.avcLevelIndication),
+		0xfc | v.lengthSizeMinusOne&0x03,
+		0xe0 | byte(len(v.sequenceParameterSetNALUnits))&0x1f,
+	}
+
+	spsBytes, err := marshalNALUList(v.sequenceParameterSetNALUnits)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal sps list")
+	}
+	b = append(b, spsBytes...)
+
+	b = append(b, byte(len(v.pictureParameterSetNALUnits)))
+	ppsBytes, err := marshalNALUList(v.pictureParameterSetNALUnits)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal pps list")
+	}
+	b = append(b, ppsBytes...)
+
+	if !hasChromaFormatIDC(v.avcProfileIndication) {
+		return b, nil
+	}
+
+	if len(v.sequenceParameterSetExtNALUnits) > 0xff {
+		return nil, errors.Errorf("numOfSequenceParameterSetExt=%v overflows uint8", len(v.sequenceParameterSetExtNALUnits))
+	}
+	b = append(b,
+		0xfc|v.chromaFormat&0x03,
+		0xf8|v.bitDepthLumaMinus8&0x07,
+		0xf8|v.bitDepthChromaMinus8&0x07,
+		byte(len(v.sequenceParameterSetExtNALUnits)),
+	)
+
+	nb, err := marshalNALUList(v.sequenceParameterSetExtNALUnits)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal sps ext")
+	}
+	b = append(b, nb...)
+
+	return b, nil
+}
+
+// marshalNALUList marshals each NALU prefixed with its 2-byte big-endian
+// length, the representation shared by the SPS, PPS and SPS-ext lists in an
+// AVCDecoderConfigurationRecord.
+func marshalNALUList(nalus []*NALU) ([]byte, error) {
+	var b []byte
+	for _, nalu := range nalus {
+		nb, err := nalu.MarshalBinary()
+		if err != nil {
+			return nil, errors.WithMessage(err, "marshal nalu")
+		}
+		if len(nb) > 0xffff {
+			return nil, errors.Errorf("nalu size=%v overflows uint16", len(nb))
+		}
+		b = append(b, byte(len(nb)>>8), byte(len(nb)))
+		b = append(b, nb...)
+	}
+	return b, nil
+}
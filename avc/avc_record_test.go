@@ -0,0 +1,137 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2013-2017 Oryx(ossrs)
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package avc
+
+import "testing"
+
+func mustUnmarshalNALU(t *testing.T, wire []byte) *NALU {
+	t.Helper()
+	nalu := NewNALU()
+	if err := nalu.UnmarshalBinary(wire); err != nil {
+		t.Fatalf("UnmarshalBinary(%v) returned error: %v", wire, err)
+	}
+	return nalu
+}
+
+func TestAVCDecoderConfigurationRecord_MarshalUnmarshal_RoundTrip_Baseline(t *testing.T) {
+	sps := mustUnmarshalNALU(t, baselineSPS)
+	pps := newTestNALU(NALUTypePPS, []byte{0xce, 0x3c, 0x80})
+
+	record := NewAVCDecoderConfigurationRecord()
+	record.avcProfileIndication = AVCProfileBaseline
+	record.constraintSetFlags = 0xc0
+	record.avcLevelIndication = AVCLevel_3
+	record.lengthSizeMinusOne = 3
+	record.sequenceParameterSetNALUnits = []*NALU{sps}
+	record.pictureParameterSetNALUnits = []*NALU{pps}
+
+	b, err := record.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary returned error: %v", err)
+	}
+
+	got := NewAVCDecoderConfigurationRecord()
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatalf("UnmarshalBinary returned error: %v", err)
+	}
+
+	if got.ProfileIndication() != AVCProfileBaseline {
+		t.Errorf("ProfileIndication() = %v, want %v", got.ProfileIndication(), AVCProfileBaseline)
+	}
+	if got.ConstraintSetFlags() != 0xc0 {
+		t.Errorf("ConstraintSetFlags() = %#x, want %#x", got.ConstraintSetFlags(), 0xc0)
+	}
+	if got.LevelIndication() != AVCLevel_3 {
+		t.Errorf("LevelIndication() = %v, want %v", got.LevelIndication(), AVCLevel_3)
+	}
+	if got.LengthSizeMinusOne() != 3 {
+		t.Errorf("LengthSizeMinusOne() = %v, want 3", got.LengthSizeMinusOne())
+	}
+	if len(got.SequenceParameterSets()) != 1 || string(got.SequenceParameterSets()[0].Data()) != string(sps.Data()) {
+		t.Errorf("SequenceParameterSets() = %v, want one SPS carrying %v", got.SequenceParameterSets(), sps.Data())
+	}
+	if len(got.PictureParameterSets()) != 1 || string(got.PictureParameterSets()[0].Data()) != string(pps.Data()) {
+		t.Errorf("PictureParameterSets() = %v, want one PPS carrying %v", got.PictureParameterSets(), pps.Data())
+	}
+}
+
+func TestAVCDecoderConfigurationRecord_MarshalUnmarshal_RoundTrip_HighProfile(t *testing.T) {
+	sps := mustUnmarshalNALU(t, highProfileSPS)
+	pps := newTestNALU(NALUTypePPS, []byte{0xce, 0x3c, 0x80})
+
+	record := NewAVCDecoderConfigurationRecord()
+	record.avcProfileIndication = AVCProfileHigh
+	record.avcLevelIndication = AVCLevel_4
+	record.lengthSizeMinusOne = 3
+	record.sequenceParameterSetNALUnits = []*NALU{sps}
+	record.pictureParameterSetNALUnits = []*NALU{pps}
+	record.chromaFormat = 1
+	record.bitDepthLumaMinus8 = 0
+	record.bitDepthChromaMinus8 = 0
+
+	b, err := record.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary returned error: %v", err)
+	}
+
+	got := NewAVCDecoderConfigurationRecord()
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatalf("UnmarshalBinary returned error: %v", err)
+	}
+
+	if got.ProfileIndication() != AVCProfileHigh {
+		t.Errorf("ProfileIndication() = %v, want %v", got.ProfileIndication(), AVCProfileHigh)
+	}
+	if got.chromaFormat != 1 || got.bitDepthLumaMinus8 != 0 || got.bitDepthChromaMinus8 != 0 {
+		t.Errorf("chroma/bit-depth fields = %v/%v/%v, want 1/0/0", got.chromaFormat, got.bitDepthLumaMinus8, got.bitDepthChromaMinus8)
+	}
+}
+
+func TestNewAVCDecoderConfigurationRecordFromNALUs(t *testing.T) {
+	sps := mustUnmarshalNALU(t, highProfileSPS)
+	pps := newTestNALU(NALUTypePPS, []byte{0xce, 0x3c, 0x80})
+
+	record, err := NewAVCDecoderConfigurationRecordFromNALUs([]*NALU{sps}, []*NALU{pps}, 3)
+	if err != nil {
+		t.Fatalf("NewAVCDecoderConfigurationRecordFromNALUs returned error: %v", err)
+	}
+
+	if record.ProfileIndication() != AVCProfileHigh {
+		t.Errorf("ProfileIndication() = %v, want %v", record.ProfileIndication(), AVCProfileHigh)
+	}
+	if record.LevelIndication() != AVCLevel_4 {
+		t.Errorf("LevelIndication() = %v, want %v", record.LevelIndication(), AVCLevel_4)
+	}
+	if record.LengthSizeMinusOne() != 3 {
+		t.Errorf("LengthSizeMinusOne() = %v, want 3", record.LengthSizeMinusOne())
+	}
+	// High profile derives its chroma/bit-depth fields from the SPS.
+	if record.chromaFormat != 1 {
+		t.Errorf("chromaFormat = %v, want 1", record.chromaFormat)
+	}
+}
+
+func TestNewAVCDecoderConfigurationRecordFromNALUs_RequiresSPS(t *testing.T) {
+	if _, err := NewAVCDecoderConfigurationRecordFromNALUs(nil, nil, 3); err == nil {
+		t.Fatal("expected error when no SPS NALUs are given")
+	}
+}
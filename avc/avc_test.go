@@ -0,0 +1,96 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2013-2017 Oryx(ossrs)
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package avc
+
+import "testing"
+
+func TestNALUType_String(t *testing.T) {
+	cases := []struct {
+		naluType NALUType
+		want     string
+	}{
+		{NALUTypeIDR, "IDR"},
+		{NALUTypeSPS, "SPS"},
+		{NALUTypeSubsetSPS, "SubsetSPS"},
+		{NALUType(200), "NALUType(200)"},
+	}
+
+	for _, c := range cases {
+		if got := c.naluType.String(); got != c.want {
+			t.Errorf("NALUType(%v).String() = %q, want %q", uint8(c.naluType), got, c.want)
+		}
+	}
+}
+
+func TestNALUType_IsVCL(t *testing.T) {
+	vcl := []NALUType{NALUTypeNonIDR, NALUTypeDataPartitionA, NALUTypeDataPartitionB,
+		NALUTypeDataPartitionC, NALUTypeIDR, NALUTypeAuxSliceWithoutPartitioning, NALUTypeSliceExt}
+	for _, naluType := range vcl {
+		if !naluType.IsVCL() {
+			t.Errorf("%v.IsVCL() = false, want true", naluType)
+		}
+	}
+
+	nonVCL := []NALUType{NALUTypeSEI, NALUTypeSPS, NALUTypePPS, NALUTypeAUD}
+	for _, naluType := range nonVCL {
+		if naluType.IsVCL() {
+			t.Errorf("%v.IsVCL() = true, want false", naluType)
+		}
+	}
+}
+
+func TestNALUType_IsParameterSet(t *testing.T) {
+	parameterSets := []NALUType{NALUTypeSPS, NALUTypePPS, NALUTypeSPSExt, NALUTypeSubsetSPS}
+	for _, naluType := range parameterSets {
+		if !naluType.IsParameterSet() {
+			t.Errorf("%v.IsParameterSet() = false, want true", naluType)
+		}
+	}
+
+	if NALUTypeIDR.IsParameterSet() {
+		t.Error("NALUTypeIDR.IsParameterSet() = true, want false")
+	}
+}
+
+func TestNALUType_IsKeyframe(t *testing.T) {
+	if !NALUTypeIDR.IsKeyframe() {
+		t.Error("NALUTypeIDR.IsKeyframe() = false, want true")
+	}
+	if NALUTypeNonIDR.IsKeyframe() {
+		t.Error("NALUTypeNonIDR.IsKeyframe() = true, want false")
+	}
+}
+
+func TestNALU_Accessors(t *testing.T) {
+	nalu := newTestNALU(NALUTypeIDR, []byte{0x01, 0x02, 0x03})
+	nalu.nalRefIDC = 2
+
+	if got := nalu.NALRefIDC(); got != 2 {
+		t.Errorf("NALRefIDC() = %v, want 2", got)
+	}
+	if got := nalu.NALUType(); got != NALUTypeIDR {
+		t.Errorf("NALUType() = %v, want %v", got, NALUTypeIDR)
+	}
+	if got := nalu.Data(); string(got) != string([]byte{0x01, 0x02, 0x03}) {
+		t.Errorf("Data() = %v, want %v", got, []byte{0x01, 0x02, 0x03})
+	}
+}
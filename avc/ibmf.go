@@ -0,0 +1,155 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2013-2017 Oryx(ossrs)
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package avc
+
+import (
+	"github.com/ossrs/go-oryx-lib/errors"
+)
+
+// SplitNALUs walks an IBMF/AVCC sample, as found in an MP4 "mdat" or FLV
+// video tag body, which is a sequence of NALUs each prefixed with a
+// NALUnitLength field of v.lengthSizeMinusOne+1 bytes, and slices out the
+// NALUs it contains. @doc ISO_IEC_14496-15-AVC-format-2012.pdf at page 19,
+// 5.3.4.2 Sample format.
+func (v *AVCDecoderConfigurationRecord) SplitNALUs(sample []byte) ([]*NALU, error) {
+	lengthSize := int(v.lengthSizeMinusOne) + 1
+	if lengthSize != 1 && lengthSize != 2 && lengthSize != 4 {
+		return nil, errors.Errorf("invalid lengthSize=%v", lengthSize)
+	}
+
+	var nalus []*NALU
+	b := sample
+	for len(b) > 0 {
+		if len(b) < lengthSize {
+			return nil, errors.Errorf("requires %v only %v bytes", lengthSize, len(b))
+		}
+
+		var length int
+		for i := 0; i < lengthSize; i++ {
+			length = length<<8 | int(b[i])
+		}
+		b = b[lengthSize:]
+
+		if len(b) < length {
+			return nil, errors.Errorf("requires %v only %v bytes", length, len(b))
+		}
+
+		nalu := newNALU()
+		if err := nalu.UnmarshalBinary(b[:length]); err != nil {
+			return nil, errors.WithMessage(err, "unmarshal nalu")
+		}
+		b = b[length:]
+
+		nalus = append(nalus, nalu)
+	}
+
+	return nalus, nil
+}
+
+// PackNALUs is the inverse of SplitNALUs, prefixing each NALU with a
+// NALUnitLength field of v.lengthSizeMinusOne+1 bytes to build an IBMF/AVCC
+// sample.
+func (v *AVCDecoderConfigurationRecord) PackNALUs(nalus []*NALU) ([]byte, error) {
+	lengthSize := int(v.lengthSizeMinusOne) + 1
+	if lengthSize != 1 && lengthSize != 2 && lengthSize != 4 {
+		return nil, errors.Errorf("invalid lengthSize=%v", lengthSize)
+	}
+
+	var sample []byte
+	for _, nalu := range nalus {
+		b, err := nalu.MarshalBinary()
+		if err != nil {
+			return nil, errors.WithMessage(err, "marshal nalu")
+		}
+
+		if lengthSize < 4 && len(b) >= 1<<uint(8*lengthSize) {
+			return nil, errors.Errorf("nalu size=%v overflows lengthSize=%v", len(b), lengthSize)
+		}
+
+		length := make([]byte, lengthSize)
+		for i := 0; i < lengthSize; i++ {
+			length[lengthSize-1-i] = byte(len(b) >> uint(8*i))
+		}
+
+		sample = append(sample, length...)
+		sample = append(sample, b...)
+	}
+
+	return sample, nil
+}
+
+// AnnexBToIBMF remuxes an Annex B elementary stream into an IBMF/AVCC sample
+// with lengthSize-byte (1, 2 or 4) NALUnitLength fields, as required when
+// writing the NALUs into an MP4 "mdat" or FLV video tag body. When
+// stripAUD is set, access unit delimiter (naluType==9) NALUs are dropped, as
+// most IBMF consumers neither expect nor need them.
+func AnnexBToIBMF(annexB []byte, lengthSize int, stripAUD bool) ([]byte, error) {
+	if lengthSize != 1 && lengthSize != 2 && lengthSize != 4 {
+		return nil, errors.Errorf("invalid lengthSize=%v", lengthSize)
+	}
+
+	nalus, err := UnmarshalAnnexB(annexB)
+	if err != nil {
+		return nil, errors.WithMessage(err, "unmarshal annexb")
+	}
+
+	const naluTypeAUD = 9
+	record := NewAVCDecoderConfigurationRecord()
+	record.lengthSizeMinusOne = uint8(lengthSize - 1)
+
+	var filtered []*NALU
+	for _, nalu := range nalus {
+		if stripAUD && nalu.naluType == naluTypeAUD {
+			continue
+		}
+		filtered = append(filtered, nalu)
+	}
+
+	sample, err := record.PackNALUs(filtered)
+	if err != nil {
+		return nil, errors.WithMessage(err, "pack nalus")
+	}
+	return sample, nil
+}
+
+// IBMFToAnnexB remuxes an IBMF/AVCC sample with lengthSize-byte (1, 2 or 4)
+// NALUnitLength fields back into an Annex B elementary stream, as required
+// when feeding the NALUs to an MPEG-TS/HLS muxer.
+func IBMFToAnnexB(sample []byte, lengthSize int) ([]byte, error) {
+	if lengthSize != 1 && lengthSize != 2 && lengthSize != 4 {
+		return nil, errors.Errorf("invalid lengthSize=%v", lengthSize)
+	}
+
+	record := NewAVCDecoderConfigurationRecord()
+	record.lengthSizeMinusOne = uint8(lengthSize - 1)
+
+	nalus, err := record.SplitNALUs(sample)
+	if err != nil {
+		return nil, errors.WithMessage(err, "split nalus")
+	}
+
+	annexB, err := MarshalAnnexB(nalus...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal annexb")
+	}
+	return annexB, nil
+}
@@ -0,0 +1,122 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2013-2017 Oryx(ossrs)
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package avc
+
+import (
+	"bytes"
+	"testing"
+)
+
+func newTestNALU(naluType NALUType, data []byte) *NALU {
+	nalu := newNALU()
+	nalu.nalRefIDC = 3
+	nalu.naluType = naluType
+	nalu.data = data
+	return nalu
+}
+
+func TestSplitPackNALUs_RoundTrip(t *testing.T) {
+	for _, lengthSize := range []int{1, 2, 4} {
+		record := NewAVCDecoderConfigurationRecord()
+		record.lengthSizeMinusOne = uint8(lengthSize - 1)
+
+		nalus := []*NALU{
+			newTestNALU(NALUTypeSPS, []byte{0x01, 0x02, 0x03}),
+			newTestNALU(NALUTypeIDR, []byte{0xaa, 0xbb}),
+		}
+
+		sample, err := record.PackNALUs(nalus)
+		if err != nil {
+			t.Fatalf("lengthSize=%v: PackNALUs returned error: %v", lengthSize, err)
+		}
+
+		got, err := record.SplitNALUs(sample)
+		if err != nil {
+			t.Fatalf("lengthSize=%v: SplitNALUs returned error: %v", lengthSize, err)
+		}
+		if len(got) != len(nalus) {
+			t.Fatalf("lengthSize=%v: SplitNALUs returned %v NALUs, want %v", lengthSize, len(got), len(nalus))
+		}
+		for i := range nalus {
+			if got[i].NALUType() != nalus[i].NALUType() || !bytes.Equal(got[i].Data(), nalus[i].Data()) {
+				t.Errorf("lengthSize=%v: nalus[%v] = %v %v, want %v %v", lengthSize, i,
+					got[i].NALUType(), got[i].Data(), nalus[i].NALUType(), nalus[i].Data())
+			}
+		}
+	}
+}
+
+func TestSplitNALUs_InvalidLengthSize(t *testing.T) {
+	record := NewAVCDecoderConfigurationRecord()
+	record.lengthSizeMinusOne = 2 // lengthSize=3, not one of 1/2/4.
+	if _, err := record.SplitNALUs([]byte{0x00, 0x00, 0x00}); err == nil {
+		t.Fatal("expected error for invalid lengthSize")
+	}
+}
+
+func TestSplitNALUs_TruncatedSample(t *testing.T) {
+	record := NewAVCDecoderConfigurationRecord()
+	record.lengthSizeMinusOne = 3 // lengthSize=4
+
+	// Length prefix claims 10 bytes follow, but only 2 are present.
+	sample := []byte{0x00, 0x00, 0x00, 0x0a, 0x01, 0x02}
+	if _, err := record.SplitNALUs(sample); err == nil {
+		t.Fatal("expected error for a NALU length prefix exceeding the remaining bytes")
+	}
+}
+
+func TestAnnexBToIBMFToAnnexB_RoundTrip(t *testing.T) {
+	aud := newTestNALU(9, nil)
+	sps := newTestNALU(NALUTypeSPS, []byte{0x01, 0x02})
+	idr := newTestNALU(NALUTypeIDR, []byte{0xaa, 0xbb, 0x00, 0x00, 0x03})
+
+	annexB, err := MarshalAnnexB(aud, sps, idr)
+	if err != nil {
+		t.Fatalf("MarshalAnnexB returned error: %v", err)
+	}
+
+	ibmf, err := AnnexBToIBMF(annexB, 4, true)
+	if err != nil {
+		t.Fatalf("AnnexBToIBMF returned error: %v", err)
+	}
+
+	back, err := IBMFToAnnexB(ibmf, 4)
+	if err != nil {
+		t.Fatalf("IBMFToAnnexB returned error: %v", err)
+	}
+
+	nalus, err := UnmarshalAnnexB(back)
+	if err != nil {
+		t.Fatalf("UnmarshalAnnexB returned error: %v", err)
+	}
+
+	// stripAUD=true should have dropped the AUD, leaving only SPS and IDR.
+	if len(nalus) != 2 {
+		t.Fatalf("got %v NALUs after stripAUD round trip, want 2", len(nalus))
+	}
+	if nalus[0].NALUType() != NALUTypeSPS || !bytes.Equal(nalus[0].Data(), sps.data) {
+		t.Errorf("nalus[0] = %v %v, want SPS %v", nalus[0].NALUType(), nalus[0].Data(), sps.data)
+	}
+	if nalus[1].NALUType() != NALUTypeIDR || !bytes.Equal(nalus[1].Data(), idr.data) {
+		t.Errorf("nalus[1] = %v %v, want IDR %v", nalus[1].NALUType(), nalus[1].Data(), idr.data)
+	}
+}
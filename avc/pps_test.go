@@ -0,0 +1,102 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2013-2017 Oryx(ossrs)
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package avc
+
+import "testing"
+
+// simplePPS has no trailing transform_8x8_mode_flag/pic_scaling_matrix tail
+// at all (more_rbsp_data() is false right after redundant_pic_cnt_present_flag).
+var simplePPS = []byte{0x68, 0xee, 0x3c, 0x80}
+
+// scalingMatrixAbsentPPS sets transform_8x8_mode_flag=0 and
+// pic_scaling_matrix_present_flag=0, but still carries a non-zero
+// second_chroma_qp_index_offset in the tail, as real streams commonly do.
+var scalingMatrixAbsentPPS = []byte{0x68, 0xee, 0x3c, 0x05, 0x40}
+
+// scalingMatrixPresentPPS sets transform_8x8_mode_flag=1 and
+// pic_scaling_matrix_present_flag=1, with all 8 scaling lists absent, and a
+// negative second_chroma_qp_index_offset.
+var scalingMatrixPresentPPS = []byte{0x68, 0xee, 0x3c, 0xc0, 0x0f}
+
+func TestPicParameterSet_UnmarshalBinary_NoTail(t *testing.T) {
+	pps := newPicParameterSet()
+	if err := pps.UnmarshalBinary(simplePPS, 1); err != nil {
+		t.Fatalf("UnmarshalBinary returned error: %v", err)
+	}
+
+	if pps.EntropyCodingModeFlag != true {
+		t.Errorf("EntropyCodingModeFlag = %v, want true", pps.EntropyCodingModeFlag)
+	}
+	if pps.DeblockingFilterControlPresentFlag != true {
+		t.Errorf("DeblockingFilterControlPresentFlag = %v, want true", pps.DeblockingFilterControlPresentFlag)
+	}
+	if pps.Transform8x8ModeFlag || pps.PicScalingMatrixPresentFlag || pps.SecondChromaQPIndexOffset != 0 {
+		t.Errorf("tail fields = %v/%v/%v, want false/false/0",
+			pps.Transform8x8ModeFlag, pps.PicScalingMatrixPresentFlag, pps.SecondChromaQPIndexOffset)
+	}
+}
+
+// TestPicParameterSet_UnmarshalBinary_SecondChromaQPWithoutScalingMatrix
+// covers the common case a reviewer caught being silently dropped:
+// second_chroma_qp_index_offset must be read whenever more_rbsp_data() is
+// true, regardless of transform_8x8_mode_flag or
+// pic_scaling_matrix_present_flag.
+func TestPicParameterSet_UnmarshalBinary_SecondChromaQPWithoutScalingMatrix(t *testing.T) {
+	pps := newPicParameterSet()
+	if err := pps.UnmarshalBinary(scalingMatrixAbsentPPS, 1); err != nil {
+		t.Fatalf("UnmarshalBinary returned error: %v", err)
+	}
+
+	if pps.Transform8x8ModeFlag {
+		t.Error("Transform8x8ModeFlag = true, want false")
+	}
+	if pps.PicScalingMatrixPresentFlag {
+		t.Error("PicScalingMatrixPresentFlag = true, want false")
+	}
+	if pps.SecondChromaQPIndexOffset != 5 {
+		t.Errorf("SecondChromaQPIndexOffset = %v, want 5", pps.SecondChromaQPIndexOffset)
+	}
+}
+
+func TestPicParameterSet_UnmarshalBinary_WithScalingMatrix(t *testing.T) {
+	pps := newPicParameterSet()
+	if err := pps.UnmarshalBinary(scalingMatrixPresentPPS, 1); err != nil {
+		t.Fatalf("UnmarshalBinary returned error: %v", err)
+	}
+
+	if !pps.Transform8x8ModeFlag {
+		t.Error("Transform8x8ModeFlag = false, want true")
+	}
+	if !pps.PicScalingMatrixPresentFlag {
+		t.Error("PicScalingMatrixPresentFlag = false, want true")
+	}
+	if pps.SecondChromaQPIndexOffset != -3 {
+		t.Errorf("SecondChromaQPIndexOffset = %v, want -3", pps.SecondChromaQPIndexOffset)
+	}
+}
+
+func TestPicParameterSet_UnmarshalBinary_RejectsWrongNALUType(t *testing.T) {
+	pps := newPicParameterSet()
+	if err := pps.UnmarshalBinary(baselineSPS, 1); err == nil {
+		t.Fatal("expected error unmarshaling an SPS NALU as a PPS")
+	}
+}
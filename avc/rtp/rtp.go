@@ -0,0 +1,279 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2013-2017 Oryx(ossrs)
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// The oryx avc/rtp package packetizes and depacketizes H.264 NALUs for RTP,
+// as defined in RFC 6184 https://tools.ietf.org/html/rfc6184. It produces
+// and consumes only the RTP payload bytes; building the RTP header/packet
+// around them is left to the caller's RTP stack.
+package rtp
+
+import (
+	"github.com/ossrs/go-oryx-lib/avc"
+	"github.com/ossrs/go-oryx-lib/errors"
+)
+
+// @doc RFC 6184, 5.2 Common Structure of the RTP Payload Format.
+const (
+	naluTypeSTAPA  = 24
+	naluTypeSTAPB  = 25
+	naluTypeMTAP16 = 26
+	naluTypeMTAP24 = 27
+	naluTypeFUA    = 28
+	naluTypeFUB    = 29
+)
+
+// Payloader packetizes a sequence of NALUs into RTP payloads no larger than
+// MTU, using single NAL unit packets when a NALU fits, STAP-A to aggregate
+// consecutive small NALUs (typically SPS+PPS+IDR), and FU-A to fragment a
+// NALU that exceeds the MTU.
+// @doc RFC 6184, 5.6 Single NAL Unit Packet, 5.7.1 Single-Time Aggregation
+// Packet type A (STAP-A), 5.8 Fragmentation Units (FUs).
+type Payloader struct {
+	MTU int
+}
+
+// NewPayloader creates a Payloader that packs RTP payloads no larger than
+// mtu bytes.
+func NewPayloader(mtu int) *Payloader {
+	return &Payloader{MTU: mtu}
+}
+
+// Payload packetizes nalus into a sequence of RTP payloads.
+func (v *Payloader) Payload(nalus []*avc.NALU) ([][]byte, error) {
+	var packets [][]byte
+
+	for i := 0; i < len(nalus); {
+		b, err := nalus[i].MarshalBinary()
+		if err != nil {
+			return nil, errors.WithMessage(err, "marshal nalu")
+		}
+
+		if len(b) > v.MTU {
+			fragments, err := v.fragment(b)
+			if err != nil {
+				return nil, errors.WithMessage(err, "fragment")
+			}
+			packets = append(packets, fragments...)
+			i++
+			continue
+		}
+
+		group := [][]byte{b}
+		size := 1 + 2 + len(b) // STAP-A indicator, plus each NALU's 2-byte size prefix.
+		j := i + 1
+		for j < len(nalus) {
+			nb, err := nalus[j].MarshalBinary()
+			if err != nil {
+				return nil, errors.WithMessage(err, "marshal nalu")
+			}
+			if len(nb) > v.MTU || size+2+len(nb) > v.MTU {
+				break
+			}
+			group = append(group, nb)
+			size += 2 + len(nb)
+			j++
+		}
+
+		if len(group) == 1 {
+			packets = append(packets, b)
+			i++
+			continue
+		}
+
+		stapA, err := aggregate(group)
+		if err != nil {
+			return nil, errors.WithMessage(err, "aggregate")
+		}
+		packets = append(packets, stapA)
+		i = j
+	}
+
+	return packets, nil
+}
+
+// aggregate builds a single STAP-A packet carrying group, each already a
+// marshaled NALU (header byte plus payload).
+func aggregate(group [][]byte) ([]byte, error) {
+	var nri uint8
+	for _, b := range group {
+		if refIDC := (b[0] >> 5) & 0x03; refIDC > nri {
+			nri = refIDC
+		}
+	}
+
+	payload := []byte{nri<<5 | naluTypeSTAPA}
+	for _, b := range group {
+		if len(b) > 0xffff {
+			return nil, errors.Errorf("nalu size=%v overflows STAP-A", len(b))
+		}
+		payload = append(payload, byte(len(b)>>8), byte(len(b)))
+		payload = append(payload, b...)
+	}
+	return payload, nil
+}
+
+// fragment splits a marshaled NALU (header byte plus payload) exceeding the
+// MTU into a sequence of FU-A packets.
+// @doc RFC 6184, 5.8 Fragmentation Units (FUs).
+func (v *Payloader) fragment(b []byte) ([][]byte, error) {
+	if v.MTU < 3 {
+		return nil, errors.Errorf("mtu=%v too small for FU-A", v.MTU)
+	}
+
+	header := b[0]
+	nri := (header >> 5) & 0x03
+	naluType := header & 0x1f
+	data := b[1:]
+
+	fuIndicator := nri<<5 | naluTypeFUA
+	chunkSize := v.MTU - 2
+
+	var packets [][]byte
+	for off := 0; off < len(data); off += chunkSize {
+		end := off + chunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		fuHeader := naluType
+		if off == 0 {
+			fuHeader |= 0x80 // Start bit.
+		}
+		if end == len(data) {
+			fuHeader |= 0x40 // End bit.
+		}
+
+		packet := make([]byte, 0, 2+end-off)
+		packet = append(packet, fuIndicator, fuHeader)
+		packet = append(packet, data[off:end]...)
+		packets = append(packets, packet)
+	}
+
+	return packets, nil
+}
+
+// NALUHandler receives each NALU reassembled by a Depayloader.
+type NALUHandler func(nalu *avc.NALU) error
+
+// Depayloader reassembles RTP payloads produced by a Payloader (or any RFC
+// 6184 compliant sender) back into whole NALUs, expanding STAP-A and
+// reassembling FU-A fragments.
+type Depayloader struct {
+	OnNALU NALUHandler
+
+	inFU        bool
+	fuIndicator byte
+	fuNALUType  byte
+	fu          []byte
+}
+
+// NewDepayloader creates a Depayloader which pushes each reassembled NALU to
+// onNALU.
+func NewDepayloader(onNALU NALUHandler) *Depayloader {
+	return &Depayloader{OnNALU: onNALU}
+}
+
+// Push feeds one RTP payload (the RTP packet's payload field, in sequence
+// number order) to the depayloader.
+func (v *Depayloader) Push(payload []byte) error {
+	if len(payload) < 1 {
+		return errors.New("empty rtp payload")
+	}
+
+	naluType := payload[0] & 0x1f
+	switch {
+	case naluType >= 1 && naluType <= 23:
+		return v.emit(payload)
+	case naluType == naluTypeSTAPA:
+		return v.unpackSTAPA(payload)
+	case naluType == naluTypeFUA:
+		return v.unpackFUA(payload)
+	case naluType == naluTypeSTAPB || naluType == naluTypeMTAP16 ||
+		naluType == naluTypeMTAP24 || naluType == naluTypeFUB:
+		return errors.Errorf("unsupported rtp nalu type=%v", naluType)
+	default:
+		return errors.Errorf("invalid rtp nalu type=%v", naluType)
+	}
+}
+
+func (v *Depayloader) emit(b []byte) error {
+	nalu := avc.NewNALU()
+	if err := nalu.UnmarshalBinary(b); err != nil {
+		return errors.WithMessage(err, "unmarshal nalu")
+	}
+	return v.OnNALU(nalu)
+}
+
+func (v *Depayloader) unpackSTAPA(payload []byte) error {
+	b := payload[1:]
+	for len(b) >= 2 {
+		size := int(b[0])<<8 | int(b[1])
+		b = b[2:]
+
+		if len(b) < size {
+			return errors.Errorf("stap-a requires %v only %v bytes", size, len(b))
+		}
+
+		if err := v.emit(b[:size]); err != nil {
+			return errors.WithMessage(err, "emit")
+		}
+		b = b[size:]
+	}
+	return nil
+}
+
+func (v *Depayloader) unpackFUA(payload []byte) error {
+	if len(payload) < 2 {
+		return errors.New("fu-a requires 2+ bytes")
+	}
+
+	indicator, header := payload[0], payload[1]
+	start := header&0x80 != 0
+	end := header&0x40 != 0
+	data := payload[2:]
+
+	if start {
+		v.inFU = true
+		v.fuIndicator = indicator
+		v.fuNALUType = header & 0x1f
+		v.fu = append([]byte{}, data...)
+	} else {
+		if !v.inFU {
+			return errors.New("fu-a continuation without start")
+		}
+		v.fu = append(v.fu, data...)
+	}
+
+	if !end {
+		return nil
+	}
+	if !v.inFU {
+		return errors.New("fu-a end without start")
+	}
+
+	nri := (v.fuIndicator >> 5) & 0x03
+	b := append([]byte{nri<<5 | v.fuNALUType}, v.fu...)
+
+	v.inFU = false
+	v.fu = nil
+
+	return v.emit(b)
+}
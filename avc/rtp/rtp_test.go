@@ -0,0 +1,148 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2013-2017 Oryx(ossrs)
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package rtp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ossrs/go-oryx-lib/avc"
+)
+
+func mustMarshal(t *testing.T, naluType avc.NALUType, data []byte) *avc.NALU {
+	t.Helper()
+	nalu := avc.NewNALU()
+	// Build the wire bytes directly: 1-byte header (nal_ref_idc=3) + data.
+	wire := append([]byte{3<<5 | byte(naluType)}, data...)
+	if err := nalu.UnmarshalBinary(wire); err != nil {
+		t.Fatalf("UnmarshalBinary returned error: %v", err)
+	}
+	return nalu
+}
+
+func TestPayloader_SingleNALU(t *testing.T) {
+	nalu := mustMarshal(t, avc.NALUTypeIDR, []byte{0x01, 0x02, 0x03})
+
+	p := NewPayloader(1500)
+	packets, err := p.Payload([]*avc.NALU{nalu})
+	if err != nil {
+		t.Fatalf("Payload returned error: %v", err)
+	}
+	if len(packets) != 1 {
+		t.Fatalf("got %v packets, want 1", len(packets))
+	}
+
+	b, err := nalu.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary returned error: %v", err)
+	}
+	if !bytes.Equal(packets[0], b) {
+		t.Errorf("packets[0] = %v, want %v (single NAL unit packet is the marshaled NALU verbatim)", packets[0], b)
+	}
+}
+
+func TestPayloader_Depayloader_RoundTrip_STAPA(t *testing.T) {
+	sps := mustMarshal(t, avc.NALUTypeSPS, []byte{0x01, 0x02})
+	pps := mustMarshal(t, avc.NALUTypePPS, []byte{0x03})
+	idr := mustMarshal(t, avc.NALUTypeIDR, []byte{0xaa, 0xbb})
+
+	p := NewPayloader(1500)
+	packets, err := p.Payload([]*avc.NALU{sps, pps, idr})
+	if err != nil {
+		t.Fatalf("Payload returned error: %v", err)
+	}
+	if len(packets) != 1 {
+		t.Fatalf("got %v packets, want 1 aggregated STAP-A", len(packets))
+	}
+
+	var got []*avc.NALU
+	d := NewDepayloader(func(nalu *avc.NALU) error {
+		got = append(got, nalu)
+		return nil
+	})
+	for _, packet := range packets {
+		if err := d.Push(packet); err != nil {
+			t.Fatalf("Push returned error: %v", err)
+		}
+	}
+
+	want := []*avc.NALU{sps, pps, idr}
+	if len(got) != len(want) {
+		t.Fatalf("got %v NALUs, want %v", len(got), len(want))
+	}
+	for i := range want {
+		wb, _ := want[i].MarshalBinary()
+		gb, _ := got[i].MarshalBinary()
+		if !bytes.Equal(wb, gb) {
+			t.Errorf("nalus[%v] = %v, want %v", i, gb, wb)
+		}
+	}
+}
+
+func TestPayloader_Depayloader_RoundTrip_FUA(t *testing.T) {
+	data := bytes.Repeat([]byte{0x42}, 5000)
+	idr := mustMarshal(t, avc.NALUTypeIDR, data)
+
+	p := NewPayloader(1000)
+	packets, err := p.Payload([]*avc.NALU{idr})
+	if err != nil {
+		t.Fatalf("Payload returned error: %v", err)
+	}
+	if len(packets) < 2 {
+		t.Fatalf("got %v packets, want a multi-packet FU-A fragmentation", len(packets))
+	}
+
+	var got *avc.NALU
+	d := NewDepayloader(func(nalu *avc.NALU) error {
+		got = nalu
+		return nil
+	})
+	for _, packet := range packets {
+		if err := d.Push(packet); err != nil {
+			t.Fatalf("Push returned error: %v", err)
+		}
+	}
+
+	if got == nil {
+		t.Fatal("Depayloader never reassembled a NALU")
+	}
+	want, _ := idr.MarshalBinary()
+	gotB, _ := got.MarshalBinary()
+	if !bytes.Equal(gotB, want) {
+		t.Errorf("reassembled NALU = %v, want %v", gotB, want)
+	}
+}
+
+func TestDepayloader_FUAContinuationWithoutStart(t *testing.T) {
+	d := NewDepayloader(func(nalu *avc.NALU) error { return nil })
+	// FU-A continuation (no start bit) before any start packet.
+	if err := d.Push([]byte{naluTypeFUA, 0x05, 0x01}); err == nil {
+		t.Fatal("expected error for FU-A continuation without a preceding start")
+	}
+}
+
+func TestDepayloader_EmptyPayload(t *testing.T) {
+	d := NewDepayloader(func(nalu *avc.NALU) error { return nil })
+	if err := d.Push(nil); err == nil {
+		t.Fatal("expected error for an empty RTP payload")
+	}
+}
@@ -0,0 +1,74 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2013-2017 Oryx(ossrs)
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package avc
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/ossrs/go-oryx-lib/errors"
+)
+
+// SPropParameterSets builds the base64 sprop-parameter-sets SDP attribute
+// value, a comma-separated list of the record's SPS then PPS NALUs, as used
+// by RFC 6184 to convey out-of-band parameter sets.
+// @doc RFC 6184, 8.2.1 Mapping of the sprop-parameter-sets Parameter to the
+// SDP sprop-parameter-sets Attribute.
+func (v *AVCDecoderConfigurationRecord) SPropParameterSets() (string, error) {
+	var parts []string
+	for _, nalus := range [][]*NALU{v.sequenceParameterSetNALUnits, v.pictureParameterSetNALUnits} {
+		for _, nalu := range nalus {
+			b, err := nalu.MarshalBinary()
+			if err != nil {
+				return "", errors.WithMessage(err, "marshal nalu")
+			}
+			parts = append(parts, base64.StdEncoding.EncodeToString(b))
+		}
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// ParseSPropParameterSets parses the base64 sprop-parameter-sets SDP
+// attribute value back into its SPS and PPS NALUs.
+func ParseSPropParameterSets(s string) (sps, pps []*NALU, err error) {
+	for _, part := range strings.Split(s, ",") {
+		b, err := base64.StdEncoding.DecodeString(part)
+		if err != nil {
+			return nil, nil, errors.WithMessage(err, "decode base64")
+		}
+
+		nalu := newNALU()
+		if err := nalu.UnmarshalBinary(b); err != nil {
+			return nil, nil, errors.WithMessage(err, "unmarshal nalu")
+		}
+
+		switch nalu.naluType {
+		case 7:
+			sps = append(sps, nalu)
+		case 8:
+			pps = append(pps, nalu)
+		default:
+			return nil, nil, errors.Errorf("unexpected naluType=%v in sprop-parameter-sets", nalu.naluType)
+		}
+	}
+	return sps, pps, nil
+}
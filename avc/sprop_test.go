@@ -0,0 +1,69 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2013-2017 Oryx(ossrs)
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package avc
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSPropParameterSets_RoundTrip(t *testing.T) {
+	sps := newTestNALU(NALUTypeSPS, []byte{0x64, 0x00, 0x28})
+	pps := newTestNALU(NALUTypePPS, []byte{0xce, 0x3c, 0x80})
+
+	record := &AVCDecoderConfigurationRecord{
+		sequenceParameterSetNALUnits: []*NALU{sps},
+		pictureParameterSetNALUnits:  []*NALU{pps},
+	}
+
+	s, err := record.SPropParameterSets()
+	if err != nil {
+		t.Fatalf("SPropParameterSets returned error: %v", err)
+	}
+
+	gotSPS, gotPPS, err := ParseSPropParameterSets(s)
+	if err != nil {
+		t.Fatalf("ParseSPropParameterSets(%q) returned error: %v", s, err)
+	}
+
+	if len(gotSPS) != 1 || gotSPS[0].NALUType() != NALUTypeSPS || !bytes.Equal(gotSPS[0].Data(), sps.data) {
+		t.Errorf("gotSPS = %v, want one SPS with data %v", gotSPS, sps.data)
+	}
+	if len(gotPPS) != 1 || gotPPS[0].NALUType() != NALUTypePPS || !bytes.Equal(gotPPS[0].Data(), pps.data) {
+		t.Errorf("gotPPS = %v, want one PPS with data %v", gotPPS, pps.data)
+	}
+}
+
+func TestParseSPropParameterSets_RejectsUnexpectedNALUType(t *testing.T) {
+	idr := newTestNALU(NALUTypeIDR, []byte{0x01})
+
+	s, err := (&AVCDecoderConfigurationRecord{
+		sequenceParameterSetNALUnits: []*NALU{idr},
+	}).SPropParameterSets()
+	if err != nil {
+		t.Fatalf("SPropParameterSets returned error: %v", err)
+	}
+
+	if _, _, err := ParseSPropParameterSets(s); err == nil {
+		t.Fatal("expected error parsing an sprop-parameter-sets entry that is neither SPS nor PPS")
+	}
+}
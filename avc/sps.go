@@ -0,0 +1,791 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2013-2017 Oryx(ossrs)
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package avc
+
+import (
+	"github.com/ossrs/go-oryx-lib/errors"
+	"github.com/ossrs/go-oryx-lib/golomb"
+)
+
+// hasChromaFormatIDC reports whether profile_idc carries the High-profile
+// chroma/bit-depth fields in the SPS, per ISO_IEC_14496-10-AVC-2003.pdf at
+// page 62, 7.3.2.1.1 Sequence parameter set data syntax (as amended).
+func hasChromaFormatIDC(profileIDC AVCProfile) bool {
+	switch profileIDC {
+	case 100, 110, 122, 144, 244, 44, 83, 86, 118, 128, 138, 139, 134, 135:
+		return true
+	default:
+		return false
+	}
+}
+
+// HRDParameters carries the subset of hrd_parameters() needed to keep the
+// bitstream position correct while parsing VUI; the CPB/DPB schedule itself
+// is not exposed because no current caller needs it.
+// @doc ISO_IEC_14496-10-AVC-2003.pdf at Annex E, E.1.2 HRD parameters syntax.
+type HRDParameters struct {
+	CPBCntMinus1                       uint32
+	BitRateScale                       uint8
+	CPBSizeScale                       uint8
+	InitialCPBRemovalDelayLengthMinus1 uint8
+	CPBRemovalDelayLengthMinus1        uint8
+	DPBOutputDelayLengthMinus1         uint8
+	TimeOffsetLength                   uint8
+}
+
+func (v *HRDParameters) unmarshal(r *golomb.BitReader) error {
+	var err error
+	if v.CPBCntMinus1, err = r.ReadUE(); err != nil {
+		return errors.WithMessage(err, "cpb_cnt_minus1")
+	}
+
+	bitRateScale, err := r.ReadBits(4)
+	if err != nil {
+		return errors.WithMessage(err, "bit_rate_scale")
+	}
+	v.BitRateScale = uint8(bitRateScale)
+
+	cpbSizeScale, err := r.ReadBits(4)
+	if err != nil {
+		return errors.WithMessage(err, "cpb_size_scale")
+	}
+	v.CPBSizeScale = uint8(cpbSizeScale)
+
+	for i := uint32(0); i <= v.CPBCntMinus1; i++ {
+		if _, err := r.ReadUE(); err != nil {
+			return errors.WithMessage(err, "bit_rate_value_minus1")
+		}
+		if _, err := r.ReadUE(); err != nil {
+			return errors.WithMessage(err, "cpb_size_value_minus1")
+		}
+		if _, err := r.ReadFlag(); err != nil {
+			return errors.WithMessage(err, "cbr_flag")
+		}
+	}
+
+	for _, p := range []*uint8{
+		&v.InitialCPBRemovalDelayLengthMinus1, &v.CPBRemovalDelayLengthMinus1,
+		&v.DPBOutputDelayLengthMinus1, &v.TimeOffsetLength,
+	} {
+		b, err := r.ReadBits(5)
+		if err != nil {
+			return errors.WithMessage(err, "hrd length field")
+		}
+		*p = uint8(b)
+	}
+
+	return nil
+}
+
+// AspectRatio is the sample_aspect_ratio signalled by the VUI, either a
+// well-known aspect_ratio_idc or, when Extended_SAR, an explicit SARWidth
+// and SARHeight.
+// @doc ISO_IEC_14496-10-AVC-2003.pdf at Annex E, Table E-1.
+type AspectRatio struct {
+	AspectRatioIDC uint8
+	SARWidth       uint16
+	SARHeight      uint16
+}
+
+// VUIParameters is the video usability information trailer of the SPS.
+// @doc ISO_IEC_14496-10-AVC-2003.pdf at Annex E, E.1.1 VUI parameters syntax.
+type VUIParameters struct {
+	AspectRatioInfoPresentFlag bool
+	AspectRatio                AspectRatio
+
+	OverscanInfoPresentFlag bool
+	OverscanAppropriateFlag bool
+
+	// The num_units_in_tick/time_scale pair used to derive the frame rate:
+	// fps = time_scale / (2 * num_units_in_tick).
+	TimingInfoPresentFlag bool
+	NumUnitsInTick        uint32
+	TimeScale             uint32
+	FixedFrameRateFlag    bool
+
+	NALHRDParametersPresentFlag bool
+	NALHRDParameters            HRDParameters
+	VCLHRDParametersPresentFlag bool
+	VCLHRDParameters            HRDParameters
+	LowDelayHRDFlag             bool
+
+	PicStructPresentFlag bool
+
+	BitstreamRestrictionFlag       bool
+	MotionVectorsOverPicBoundaries bool
+	MaxBytesPerPicDenom            uint32
+	MaxBitsPerMBDenom              uint32
+	Log2MaxMVLengthHorizontal      uint32
+	Log2MaxMVLengthVertical        uint32
+	MaxNumReorderFrames            uint32
+	MaxDecFrameBuffering           uint32
+}
+
+// FPS returns the frame rate derived from the timing info, or 0 if the VUI
+// does not carry timing info.
+func (v *VUIParameters) FPS() float64 {
+	if !v.TimingInfoPresentFlag || v.NumUnitsInTick == 0 {
+		return 0
+	}
+	return float64(v.TimeScale) / (2 * float64(v.NumUnitsInTick))
+}
+
+func (v *VUIParameters) unmarshal(r *golomb.BitReader) error {
+	var err error
+	if v.AspectRatioInfoPresentFlag, err = r.ReadFlag(); err != nil {
+		return errors.WithMessage(err, "aspect_ratio_info_present_flag")
+	}
+	if v.AspectRatioInfoPresentFlag {
+		idc, err := r.ReadBits(8)
+		if err != nil {
+			return errors.WithMessage(err, "aspect_ratio_idc")
+		}
+		v.AspectRatio.AspectRatioIDC = uint8(idc)
+
+		const extendedSAR = 255
+		if v.AspectRatio.AspectRatioIDC == extendedSAR {
+			w, err := r.ReadBits(16)
+			if err != nil {
+				return errors.WithMessage(err, "sar_width")
+			}
+			h, err := r.ReadBits(16)
+			if err != nil {
+				return errors.WithMessage(err, "sar_height")
+			}
+			v.AspectRatio.SARWidth, v.AspectRatio.SARHeight = uint16(w), uint16(h)
+		}
+	}
+
+	if v.OverscanInfoPresentFlag, err = r.ReadFlag(); err != nil {
+		return errors.WithMessage(err, "overscan_info_present_flag")
+	}
+	if v.OverscanInfoPresentFlag {
+		if v.OverscanAppropriateFlag, err = r.ReadFlag(); err != nil {
+			return errors.WithMessage(err, "overscan_appropriate_flag")
+		}
+	}
+
+	videoSignalTypePresentFlag, err := r.ReadFlag()
+	if err != nil {
+		return errors.WithMessage(err, "video_signal_type_present_flag")
+	}
+	if videoSignalTypePresentFlag {
+		if _, err := r.ReadBits(3); err != nil {
+			return errors.WithMessage(err, "video_format")
+		}
+		if _, err := r.ReadFlag(); err != nil {
+			return errors.WithMessage(err, "video_full_range_flag")
+		}
+		colourDescriptionPresentFlag, err := r.ReadFlag()
+		if err != nil {
+			return errors.WithMessage(err, "colour_description_present_flag")
+		}
+		if colourDescriptionPresentFlag {
+			if _, err := r.ReadBits(8); err != nil {
+				return errors.WithMessage(err, "colour_primaries")
+			}
+			if _, err := r.ReadBits(8); err != nil {
+				return errors.WithMessage(err, "transfer_characteristics")
+			}
+			if _, err := r.ReadBits(8); err != nil {
+				return errors.WithMessage(err, "matrix_coefficients")
+			}
+		}
+	}
+
+	chromaLocInfoPresentFlag, err := r.ReadFlag()
+	if err != nil {
+		return errors.WithMessage(err, "chroma_loc_info_present_flag")
+	}
+	if chromaLocInfoPresentFlag {
+		if _, err := r.ReadUE(); err != nil {
+			return errors.WithMessage(err, "chroma_sample_loc_type_top_field")
+		}
+		if _, err := r.ReadUE(); err != nil {
+			return errors.WithMessage(err, "chroma_sample_loc_type_bottom_field")
+		}
+	}
+
+	if v.TimingInfoPresentFlag, err = r.ReadFlag(); err != nil {
+		return errors.WithMessage(err, "timing_info_present_flag")
+	}
+	if v.TimingInfoPresentFlag {
+		if v.NumUnitsInTick, err = r.ReadBits(32); err != nil {
+			return errors.WithMessage(err, "num_units_in_tick")
+		}
+		if v.TimeScale, err = r.ReadBits(32); err != nil {
+			return errors.WithMessage(err, "time_scale")
+		}
+		if v.FixedFrameRateFlag, err = r.ReadFlag(); err != nil {
+			return errors.WithMessage(err, "fixed_frame_rate_flag")
+		}
+	}
+
+	if v.NALHRDParametersPresentFlag, err = r.ReadFlag(); err != nil {
+		return errors.WithMessage(err, "nal_hrd_parameters_present_flag")
+	}
+	if v.NALHRDParametersPresentFlag {
+		if err := v.NALHRDParameters.unmarshal(r); err != nil {
+			return errors.WithMessage(err, "nal hrd_parameters")
+		}
+	}
+
+	if v.VCLHRDParametersPresentFlag, err = r.ReadFlag(); err != nil {
+		return errors.WithMessage(err, "vcl_hrd_parameters_present_flag")
+	}
+	if v.VCLHRDParametersPresentFlag {
+		if err := v.VCLHRDParameters.unmarshal(r); err != nil {
+			return errors.WithMessage(err, "vcl hrd_parameters")
+		}
+	}
+
+	if v.NALHRDParametersPresentFlag || v.VCLHRDParametersPresentFlag {
+		if v.LowDelayHRDFlag, err = r.ReadFlag(); err != nil {
+			return errors.WithMessage(err, "low_delay_hrd_flag")
+		}
+	}
+
+	if v.PicStructPresentFlag, err = r.ReadFlag(); err != nil {
+		return errors.WithMessage(err, "pic_struct_present_flag")
+	}
+
+	if v.BitstreamRestrictionFlag, err = r.ReadFlag(); err != nil {
+		return errors.WithMessage(err, "bitstream_restriction_flag")
+	}
+	if v.BitstreamRestrictionFlag {
+		if v.MotionVectorsOverPicBoundaries, err = r.ReadFlag(); err != nil {
+			return errors.WithMessage(err, "motion_vectors_over_pic_boundaries_flag")
+		}
+		if v.MaxBytesPerPicDenom, err = r.ReadUE(); err != nil {
+			return errors.WithMessage(err, "max_bytes_per_pic_denom")
+		}
+		if v.MaxBitsPerMBDenom, err = r.ReadUE(); err != nil {
+			return errors.WithMessage(err, "max_bits_per_mb_denom")
+		}
+		if v.Log2MaxMVLengthHorizontal, err = r.ReadUE(); err != nil {
+			return errors.WithMessage(err, "log2_max_mv_length_horizontal")
+		}
+		if v.Log2MaxMVLengthVertical, err = r.ReadUE(); err != nil {
+			return errors.WithMessage(err, "log2_max_mv_length_vertical")
+		}
+		if v.MaxNumReorderFrames, err = r.ReadUE(); err != nil {
+			return errors.WithMessage(err, "max_num_reorder_frames")
+		}
+		if v.MaxDecFrameBuffering, err = r.ReadUE(); err != nil {
+			return errors.WithMessage(err, "max_dec_frame_buffering")
+		}
+	}
+
+	return nil
+}
+
+// skipScalingList consumes a scaling_list(size) element, discarding its
+// values since no current caller needs the coefficients, only the bit
+// position to stay correct for the fields that follow.
+// @doc ISO_IEC_14496-10-AVC-2003.pdf at page 63, 7.3.2.1.1.1 Scaling list
+// syntax.
+func skipScalingList(r *golomb.BitReader, size int) error {
+	lastScale, nextScale := int32(8), int32(8)
+	for j := 0; j < size; j++ {
+		if nextScale != 0 {
+			deltaScale, err := r.ReadSE()
+			if err != nil {
+				return errors.WithMessage(err, "delta_scale")
+			}
+			nextScale = (lastScale + deltaScale + 256) % 256
+		}
+		if nextScale != 0 {
+			lastScale = nextScale
+		}
+	}
+	return nil
+}
+
+// SeqParameterSet is the parsed seq_parameter_set_rbsp() of a naluType==7
+// NALU.
+// @doc ISO_IEC_14496-10-AVC-2003.pdf at page 62, 7.3.2.1.1 Sequence
+// parameter set data syntax.
+type SeqParameterSet struct {
+	ProfileIDC AVCProfile
+	// The byte between profile_idc and level_idc, that is the
+	// constraint_set0_flag..constraint_set5_flag plus 2 reserved bits.
+	// @remark This is the constraint_set flags byte, not the
+	// profile_compatibility byte of some other spec version.
+	ConstraintSetFlags uint8
+	LevelIDC           AVCLevel
+	SeqParameterSetID  uint32
+
+	ChromaFormatIDC                 uint32
+	SeparateColourPlaneFlag         bool
+	BitDepthLumaMinus8              uint32
+	BitDepthChromaMinus8            uint32
+	QPPrimeYZeroTransformBypassFlag bool
+
+	Log2MaxFrameNum uint32
+
+	PicOrderCntType             uint32
+	Log2MaxPicOrderCntLsb       uint32
+	DeltaPicOrderAlwaysZeroFlag bool
+	OffsetForNonRefPic          int32
+	OffsetForTopToBottomField   int32
+	OffsetForRefFrame           []int32
+
+	MaxNumRefFrames            uint32
+	GapsInFrameNumValueAllowed bool
+
+	PicWidthInMbsMinus1       uint32
+	PicHeightInMapUnitsMinus1 uint32
+	FrameMbsOnlyFlag          bool
+	MBAdaptiveFrameFieldFlag  bool
+	Direct8x8InferenceFlag    bool
+
+	FrameCroppingFlag bool
+	FrameCropLeft     uint32
+	FrameCropRight    uint32
+	FrameCropTop      uint32
+	FrameCropBottom   uint32
+
+	VUIParametersPresentFlag bool
+	VUI                      VUIParameters
+}
+
+func newSeqParameterSet() *SeqParameterSet {
+	return &SeqParameterSet{}
+}
+
+// UnmarshalBinary parses the RBSP of a naluType==7 (SPS) NALU. data is the
+// NALU payload, including the NALU header byte; emulation-prevention bytes
+// are unescaped internally.
+func (v *SeqParameterSet) UnmarshalBinary(data []byte) error {
+	nalu := newNALU()
+	if err := nalu.UnmarshalBinary(data); err != nil {
+		return errors.WithMessage(err, "unmarshal nalu")
+	}
+	const naluTypeSPS = 7
+	if nalu.naluType != naluTypeSPS {
+		return errors.Errorf("naluType=%v is not SPS", nalu.naluType)
+	}
+
+	r := golomb.NewBitReader(UnescapeEmulationPrevention(nalu.data))
+
+	profileIDC, err := r.ReadBits(8)
+	if err != nil {
+		return errors.WithMessage(err, "profile_idc")
+	}
+	v.ProfileIDC = AVCProfile(profileIDC)
+
+	constraintSetFlags, err := r.ReadBits(8)
+	if err != nil {
+		return errors.WithMessage(err, "constraint_set_flags")
+	}
+	v.ConstraintSetFlags = uint8(constraintSetFlags)
+
+	levelIDC, err := r.ReadBits(8)
+	if err != nil {
+		return errors.WithMessage(err, "level_idc")
+	}
+	v.LevelIDC = AVCLevel(levelIDC)
+
+	if v.SeqParameterSetID, err = r.ReadUE(); err != nil {
+		return errors.WithMessage(err, "seq_parameter_set_id")
+	}
+
+	v.ChromaFormatIDC = 1 // Default to 4:2:0 when not signalled.
+	if hasChromaFormatIDC(v.ProfileIDC) {
+		if v.ChromaFormatIDC, err = r.ReadUE(); err != nil {
+			return errors.WithMessage(err, "chroma_format_idc")
+		}
+		if v.ChromaFormatIDC == 3 {
+			if v.SeparateColourPlaneFlag, err = r.ReadFlag(); err != nil {
+				return errors.WithMessage(err, "separate_colour_plane_flag")
+			}
+		}
+		if v.BitDepthLumaMinus8, err = r.ReadUE(); err != nil {
+			return errors.WithMessage(err, "bit_depth_luma_minus8")
+		}
+		if v.BitDepthChromaMinus8, err = r.ReadUE(); err != nil {
+			return errors.WithMessage(err, "bit_depth_chroma_minus8")
+		}
+		if v.QPPrimeYZeroTransformBypassFlag, err = r.ReadFlag(); err != nil {
+			return errors.WithMessage(err, "qpprime_y_zero_transform_bypass_flag")
+		}
+
+		seqScalingMatrixPresentFlag, err := r.ReadFlag()
+		if err != nil {
+			return errors.WithMessage(err, "seq_scaling_matrix_present_flag")
+		}
+		if seqScalingMatrixPresentFlag {
+			n := 8
+			if v.ChromaFormatIDC == 3 {
+				n = 12
+			}
+			for i := 0; i < n; i++ {
+				present, err := r.ReadFlag()
+				if err != nil {
+					return errors.WithMessage(err, "seq_scaling_list_present_flag")
+				}
+				if !present {
+					continue
+				}
+				size := 16
+				if i >= 6 {
+					size = 64
+				}
+				if err := skipScalingList(r, size); err != nil {
+					return errors.WithMessage(err, "scaling_list")
+				}
+			}
+		}
+	}
+
+	log2MaxFrameNumMinus4, err := r.ReadUE()
+	if err != nil {
+		return errors.WithMessage(err, "log2_max_frame_num_minus4")
+	}
+	v.Log2MaxFrameNum = log2MaxFrameNumMinus4 + 4
+
+	if v.PicOrderCntType, err = r.ReadUE(); err != nil {
+		return errors.WithMessage(err, "pic_order_cnt_type")
+	}
+	switch v.PicOrderCntType {
+	case 0:
+		log2MaxPicOrderCntLsbMinus4, err := r.ReadUE()
+		if err != nil {
+			return errors.WithMessage(err, "log2_max_pic_order_cnt_lsb_minus4")
+		}
+		v.Log2MaxPicOrderCntLsb = log2MaxPicOrderCntLsbMinus4 + 4
+	case 1:
+		if v.DeltaPicOrderAlwaysZeroFlag, err = r.ReadFlag(); err != nil {
+			return errors.WithMessage(err, "delta_pic_order_always_zero_flag")
+		}
+		if v.OffsetForNonRefPic, err = r.ReadSE(); err != nil {
+			return errors.WithMessage(err, "offset_for_non_ref_pic")
+		}
+		if v.OffsetForTopToBottomField, err = r.ReadSE(); err != nil {
+			return errors.WithMessage(err, "offset_for_top_to_bottom_field")
+		}
+		numRefFramesInPicOrderCntCycle, err := r.ReadUE()
+		if err != nil {
+			return errors.WithMessage(err, "num_ref_frames_in_pic_order_cnt_cycle")
+		}
+		for i := uint32(0); i < numRefFramesInPicOrderCntCycle; i++ {
+			offset, err := r.ReadSE()
+			if err != nil {
+				return errors.WithMessage(err, "offset_for_ref_frame")
+			}
+			v.OffsetForRefFrame = append(v.OffsetForRefFrame, offset)
+		}
+	}
+
+	if v.MaxNumRefFrames, err = r.ReadUE(); err != nil {
+		return errors.WithMessage(err, "max_num_ref_frames")
+	}
+	if v.GapsInFrameNumValueAllowed, err = r.ReadFlag(); err != nil {
+		return errors.WithMessage(err, "gaps_in_frame_num_value_allowed_flag")
+	}
+	if v.PicWidthInMbsMinus1, err = r.ReadUE(); err != nil {
+		return errors.WithMessage(err, "pic_width_in_mbs_minus1")
+	}
+	if v.PicHeightInMapUnitsMinus1, err = r.ReadUE(); err != nil {
+		return errors.WithMessage(err, "pic_height_in_map_units_minus1")
+	}
+	if v.FrameMbsOnlyFlag, err = r.ReadFlag(); err != nil {
+		return errors.WithMessage(err, "frame_mbs_only_flag")
+	}
+	if !v.FrameMbsOnlyFlag {
+		if v.MBAdaptiveFrameFieldFlag, err = r.ReadFlag(); err != nil {
+			return errors.WithMessage(err, "mb_adaptive_frame_field_flag")
+		}
+	}
+	if v.Direct8x8InferenceFlag, err = r.ReadFlag(); err != nil {
+		return errors.WithMessage(err, "direct_8x8_inference_flag")
+	}
+
+	if v.FrameCroppingFlag, err = r.ReadFlag(); err != nil {
+		return errors.WithMessage(err, "frame_cropping_flag")
+	}
+	if v.FrameCroppingFlag {
+		if v.FrameCropLeft, err = r.ReadUE(); err != nil {
+			return errors.WithMessage(err, "frame_crop_left_offset")
+		}
+		if v.FrameCropRight, err = r.ReadUE(); err != nil {
+			return errors.WithMessage(err, "frame_crop_right_offset")
+		}
+		if v.FrameCropTop, err = r.ReadUE(); err != nil {
+			return errors.WithMessage(err, "frame_crop_top_offset")
+		}
+		if v.FrameCropBottom, err = r.ReadUE(); err != nil {
+			return errors.WithMessage(err, "frame_crop_bottom_offset")
+		}
+	}
+
+	if v.VUIParametersPresentFlag, err = r.ReadFlag(); err != nil {
+		return errors.WithMessage(err, "vui_parameters_present_flag")
+	}
+	if v.VUIParametersPresentFlag {
+		if err := v.VUI.unmarshal(r); err != nil {
+			return errors.WithMessage(err, "vui_parameters")
+		}
+	}
+
+	return nil
+}
+
+// Dimensions returns the coded picture size after applying the chroma
+// subsampling and frame cropping, per ISO_IEC_14496-10-AVC-2003.pdf at page
+// 76, equations 7-19 through 7-28.
+func (v *SeqParameterSet) Dimensions() (width, height int) {
+	width = int(v.PicWidthInMbsMinus1+1) * 16
+
+	frameMbsOnly := 0
+	if v.FrameMbsOnlyFlag {
+		frameMbsOnly = 1
+	}
+	height = int(v.PicHeightInMapUnitsMinus1+1) * 16 * (2 - frameMbsOnly)
+
+	if !v.FrameCroppingFlag {
+		return
+	}
+
+	chromaArrayType := v.ChromaFormatIDC
+	if v.SeparateColourPlaneFlag {
+		chromaArrayType = 0
+	}
+
+	subWidthC, subHeightC := 1, 1
+	switch v.ChromaFormatIDC {
+	case 1: // 4:2:0
+		subWidthC, subHeightC = 2, 2
+	case 2: // 4:2:2
+		subWidthC, subHeightC = 2, 1
+	case 3: // 4:4:4
+		subWidthC, subHeightC = 1, 1
+	}
+
+	cropUnitX, cropUnitY := 1, 2-frameMbsOnly
+	if chromaArrayType != 0 {
+		cropUnitX = subWidthC
+		cropUnitY = subHeightC * (2 - frameMbsOnly)
+	}
+
+	width -= cropUnitX * int(v.FrameCropLeft+v.FrameCropRight)
+	height -= cropUnitY * int(v.FrameCropTop+v.FrameCropBottom)
+	return
+}
+
+// ceilLog2 returns Ceil(Log2(n)) for n >= 1, as used to size the
+// slice_group_id[i] field.
+func ceilLog2(n uint32) int {
+	r := 0
+	for v := uint32(1); v < n; v <<= 1 {
+		r++
+	}
+	return r
+}
+
+// PicParameterSet is the parsed pic_parameter_set_rbsp() of a naluType==8
+// NALU.
+// @doc ISO_IEC_14496-10-AVC-2003.pdf at page 64, 7.3.2.2 Picture parameter
+// set RBSP syntax.
+type PicParameterSet struct {
+	PicParameterSetID uint32
+	SeqParameterSetID uint32
+
+	EntropyCodingModeFlag                 bool
+	BottomFieldPicOrderInFramePresentFlag bool
+	NumSliceGroupsMinus1                  uint32
+
+	NumRefIdxL0DefaultActiveMinus1 uint32
+	NumRefIdxL1DefaultActiveMinus1 uint32
+	WeightedPredFlag               bool
+	WeightedBipredIDC              uint32
+
+	PicInitQPMinus26    int32
+	PicInitQSMinus26    int32
+	ChromaQPIndexOffset int32
+
+	DeblockingFilterControlPresentFlag bool
+	ConstrainedIntraPredFlag           bool
+	RedundantPicCntPresentFlag         bool
+
+	Transform8x8ModeFlag        bool
+	PicScalingMatrixPresentFlag bool
+	SecondChromaQPIndexOffset   int32
+}
+
+func newPicParameterSet() *PicParameterSet {
+	return &PicParameterSet{}
+}
+
+// UnmarshalBinary parses the RBSP of a naluType==8 (PPS) NALU. data is the
+// NALU payload, including the NALU header byte; chromaFormatIDC must come
+// from the active SeqParameterSet, since the PPS scaling matrix list count
+// depends on it. data's emulation-prevention bytes are unescaped internally.
+func (v *PicParameterSet) UnmarshalBinary(data []byte, chromaFormatIDC uint32) error {
+	nalu := newNALU()
+	if err := nalu.UnmarshalBinary(data); err != nil {
+		return errors.WithMessage(err, "unmarshal nalu")
+	}
+	const naluTypePPS = 8
+	if nalu.naluType != naluTypePPS {
+		return errors.Errorf("naluType=%v is not PPS", nalu.naluType)
+	}
+
+	r := golomb.NewBitReader(UnescapeEmulationPrevention(nalu.data))
+
+	var err error
+	if v.PicParameterSetID, err = r.ReadUE(); err != nil {
+		return errors.WithMessage(err, "pic_parameter_set_id")
+	}
+	if v.SeqParameterSetID, err = r.ReadUE(); err != nil {
+		return errors.WithMessage(err, "seq_parameter_set_id")
+	}
+	if v.EntropyCodingModeFlag, err = r.ReadFlag(); err != nil {
+		return errors.WithMessage(err, "entropy_coding_mode_flag")
+	}
+	if v.BottomFieldPicOrderInFramePresentFlag, err = r.ReadFlag(); err != nil {
+		return errors.WithMessage(err, "bottom_field_pic_order_in_frame_present_flag")
+	}
+	if v.NumSliceGroupsMinus1, err = r.ReadUE(); err != nil {
+		return errors.WithMessage(err, "num_slice_groups_minus1")
+	}
+
+	if v.NumSliceGroupsMinus1 > 0 {
+		sliceGroupMapType, err := r.ReadUE()
+		if err != nil {
+			return errors.WithMessage(err, "slice_group_map_type")
+		}
+		switch sliceGroupMapType {
+		case 0:
+			for i := uint32(0); i <= v.NumSliceGroupsMinus1; i++ {
+				if _, err := r.ReadUE(); err != nil {
+					return errors.WithMessage(err, "run_length_minus1")
+				}
+			}
+		case 2:
+			for i := uint32(0); i < v.NumSliceGroupsMinus1; i++ {
+				if _, err := r.ReadUE(); err != nil {
+					return errors.WithMessage(err, "top_left")
+				}
+				if _, err := r.ReadUE(); err != nil {
+					return errors.WithMessage(err, "bottom_right")
+				}
+			}
+		case 3, 4, 5:
+			if _, err := r.ReadFlag(); err != nil {
+				return errors.WithMessage(err, "slice_group_change_direction_flag")
+			}
+			if _, err := r.ReadUE(); err != nil {
+				return errors.WithMessage(err, "slice_group_change_rate_minus1")
+			}
+		case 6:
+			picSizeInMapUnitsMinus1, err := r.ReadUE()
+			if err != nil {
+				return errors.WithMessage(err, "pic_size_in_map_units_minus1")
+			}
+			bits := ceilLog2(v.NumSliceGroupsMinus1 + 1)
+			for i := uint32(0); i <= picSizeInMapUnitsMinus1; i++ {
+				if _, err := r.ReadBits(bits); err != nil {
+					return errors.WithMessage(err, "slice_group_id")
+				}
+			}
+		}
+	}
+
+	if v.NumRefIdxL0DefaultActiveMinus1, err = r.ReadUE(); err != nil {
+		return errors.WithMessage(err, "num_ref_idx_l0_default_active_minus1")
+	}
+	if v.NumRefIdxL1DefaultActiveMinus1, err = r.ReadUE(); err != nil {
+		return errors.WithMessage(err, "num_ref_idx_l1_default_active_minus1")
+	}
+	if v.WeightedPredFlag, err = r.ReadFlag(); err != nil {
+		return errors.WithMessage(err, "weighted_pred_flag")
+	}
+	if v.WeightedBipredIDC, err = r.ReadBits(2); err != nil {
+		return errors.WithMessage(err, "weighted_bipred_idc")
+	}
+	if v.PicInitQPMinus26, err = r.ReadSE(); err != nil {
+		return errors.WithMessage(err, "pic_init_qp_minus26")
+	}
+	if v.PicInitQSMinus26, err = r.ReadSE(); err != nil {
+		return errors.WithMessage(err, "pic_init_qs_minus26")
+	}
+	if v.ChromaQPIndexOffset, err = r.ReadSE(); err != nil {
+		return errors.WithMessage(err, "chroma_qp_index_offset")
+	}
+	if v.DeblockingFilterControlPresentFlag, err = r.ReadFlag(); err != nil {
+		return errors.WithMessage(err, "deblocking_filter_control_present_flag")
+	}
+	if v.ConstrainedIntraPredFlag, err = r.ReadFlag(); err != nil {
+		return errors.WithMessage(err, "constrained_intra_pred_flag")
+	}
+	if v.RedundantPicCntPresentFlag, err = r.ReadFlag(); err != nil {
+		return errors.WithMessage(err, "redundant_pic_cnt_present_flag")
+	}
+
+	if !r.MoreRBSPData() {
+		return nil
+	}
+
+	if v.Transform8x8ModeFlag, err = r.ReadFlag(); err != nil {
+		return errors.WithMessage(err, "transform_8x8_mode_flag")
+	}
+	if v.PicScalingMatrixPresentFlag, err = r.ReadFlag(); err != nil {
+		return errors.WithMessage(err, "pic_scaling_matrix_present_flag")
+	}
+	if v.PicScalingMatrixPresentFlag {
+		n := 6
+		if chromaFormatIDC != 3 {
+			n += 2 * boolToInt(v.Transform8x8ModeFlag)
+		} else {
+			n += 6 * boolToInt(v.Transform8x8ModeFlag)
+		}
+		for i := 0; i < n; i++ {
+			present, err := r.ReadFlag()
+			if err != nil {
+				return errors.WithMessage(err, "pic_scaling_list_present_flag")
+			}
+			if !present {
+				continue
+			}
+			size := 16
+			if i >= 6 {
+				size = 64
+			}
+			if err := skipScalingList(r, size); err != nil {
+				return errors.WithMessage(err, "scaling_list")
+			}
+		}
+	}
+	if v.SecondChromaQPIndexOffset, err = r.ReadSE(); err != nil {
+		return errors.WithMessage(err, "second_chroma_qp_index_offset")
+	}
+
+	return nil
+}
+
+func boolToInt(v bool) int {
+	if v {
+		return 1
+	}
+	return 0
+}
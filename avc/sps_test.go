@@ -0,0 +1,109 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2013-2017 Oryx(ossrs)
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package avc
+
+import (
+	"testing"
+)
+
+// baselineSPS is a 320x240 Baseline profile SPS with no VUI.
+var baselineSPS = []byte{0x67, 0x42, 0xc0, 0x1e, 0xd9, 0x01, 0x41, 0xf9}
+
+// highProfileSPS is a 1280x720 High profile SPS, cropped to 1280x712, with a
+// VUI carrying a 30fps timing_info.
+var highProfileSPS = []byte{
+	0x67, 0x64, 0x00, 0x28, 0xac, 0xe5, 0x01, 0x40, 0x16, 0xfe, 0x5c, 0x04,
+	0x40, 0x00, 0x00, 0x03, 0x00, 0x40, 0x00, 0x00, 0x0f, 0x21,
+}
+
+func TestSeqParameterSet_UnmarshalBinary_Baseline(t *testing.T) {
+	sps := newSeqParameterSet()
+	if err := sps.UnmarshalBinary(baselineSPS); err != nil {
+		t.Fatalf("UnmarshalBinary returned error: %v", err)
+	}
+
+	if sps.ProfileIDC != AVCProfileBaseline {
+		t.Errorf("ProfileIDC = %v, want %v", sps.ProfileIDC, AVCProfileBaseline)
+	}
+	if sps.LevelIDC != AVCLevel_3 {
+		t.Errorf("LevelIDC = %v, want %v", sps.LevelIDC, AVCLevel_3)
+	}
+	if sps.ChromaFormatIDC != 1 {
+		t.Errorf("ChromaFormatIDC = %v, want 1 (default 4:2:0)", sps.ChromaFormatIDC)
+	}
+	if sps.FrameCroppingFlag {
+		t.Error("FrameCroppingFlag = true, want false")
+	}
+	if sps.VUIParametersPresentFlag {
+		t.Error("VUIParametersPresentFlag = true, want false")
+	}
+
+	w, h := sps.Dimensions()
+	if w != 320 || h != 240 {
+		t.Errorf("Dimensions() = %vx%v, want 320x240", w, h)
+	}
+}
+
+func TestSeqParameterSet_UnmarshalBinary_HighProfile(t *testing.T) {
+	sps := newSeqParameterSet()
+	if err := sps.UnmarshalBinary(highProfileSPS); err != nil {
+		t.Fatalf("UnmarshalBinary returned error: %v", err)
+	}
+
+	if sps.ProfileIDC != AVCProfileHigh {
+		t.Errorf("ProfileIDC = %v, want %v", sps.ProfileIDC, AVCProfileHigh)
+	}
+	if sps.LevelIDC != AVCLevel_4 {
+		t.Errorf("LevelIDC = %v, want %v", sps.LevelIDC, AVCLevel_4)
+	}
+	if sps.ChromaFormatIDC != 1 {
+		t.Errorf("ChromaFormatIDC = %v, want 1", sps.ChromaFormatIDC)
+	}
+	if sps.BitDepthLumaMinus8 != 0 || sps.BitDepthChromaMinus8 != 0 {
+		t.Errorf("BitDepth{Luma,Chroma}Minus8 = %v/%v, want 0/0",
+			sps.BitDepthLumaMinus8, sps.BitDepthChromaMinus8)
+	}
+	if !sps.FrameCroppingFlag {
+		t.Fatal("FrameCroppingFlag = false, want true")
+	}
+	if sps.FrameCropBottom != 4 {
+		t.Errorf("FrameCropBottom = %v, want 4", sps.FrameCropBottom)
+	}
+
+	w, h := sps.Dimensions()
+	if w != 1280 || h != 712 {
+		t.Errorf("Dimensions() = %vx%v, want 1280x712", w, h)
+	}
+
+	if !sps.VUIParametersPresentFlag {
+		t.Fatal("VUIParametersPresentFlag = false, want true")
+	}
+	if !sps.VUI.TimingInfoPresentFlag {
+		t.Fatal("VUI.TimingInfoPresentFlag = false, want true")
+	}
+	if fps := sps.VUI.FPS(); fps != 30 {
+		t.Errorf("VUI.FPS() = %v, want 30", fps)
+	}
+	if sps.VUI.AspectRatio.AspectRatioIDC != 1 {
+		t.Errorf("VUI.AspectRatio.AspectRatioIDC = %v, want 1", sps.VUI.AspectRatio.AspectRatioIDC)
+	}
+}
@@ -0,0 +1,324 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2013-2017 Oryx(ossrs)
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// The oryx EVC package includes some utilites for MPEG-5 Essential Video
+// Coding (ISO/IEC 23094-1), the peer of the avc and hevc packages, mostly
+// useful to MP4/ISOBMFF muxers writing EVC tracks.
+//	@note IBMF is designed for file storage, such as MP4, please read the
+//		ISOBMFF binding for EVC, ISO_IEC_14496-15-2019-Amd2.pdf, EVC decoder
+//		configuration record.
+package evc
+
+import (
+	"bytes"
+
+	"github.com/ossrs/go-oryx-lib/errors"
+)
+
+// NALUType is the nal_unit_type of the 2-byte EVC NAL unit header, derived
+// from nal_unit_type_plus1 - 1.
+type NALUType uint8
+
+const (
+	NALUTypeNonIDR NALUType = 0
+	NALUTypeIDR    NALUType = 1
+	NALUTypeSPS    NALUType = 24
+	NALUTypePPS    NALUType = 25
+	NALUTypeAPS    NALUType = 26
+	NALUTypeFD     NALUType = 27
+	NALUTypeSEI    NALUType = 28
+)
+
+// @doc ISO_IEC_23094-1 MPEG-5 EVC, 7.3.2 NAL unit syntax.
+type NALUHeader struct {
+	// The 1-bit forbidden_zero_bit, always 0.
+	forbiddenZeroBit uint8
+	// The 6-bit nal_unit_type_plus1; the actual NALUType is this minus one.
+	naluTypePlus1 uint8
+	// The 3-bit nuh_temporal_id.
+	nuhTemporalID uint8
+	// The 5-bit nuh_reserved_zero_5bits.
+	nuhReservedZero5Bits uint8
+	// The 1-bit nuh_extension_flag.
+	nuhExtensionFlag bool
+}
+
+func newNALUHeader() *NALUHeader {
+	return &NALUHeader{}
+}
+
+func (v *NALUHeader) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 {
+		return errors.Errorf("requires 2+ only %v bytes", len(data))
+	}
+
+	val := uint16(data[0])<<8 | uint16(data[1])
+	v.forbiddenZeroBit = uint8((val >> 15) & 0x01)
+	v.naluTypePlus1 = uint8((val >> 9) & 0x3f)
+	v.nuhTemporalID = uint8((val >> 6) & 0x07)
+	v.nuhReservedZero5Bits = uint8((val >> 1) & 0x1f)
+	v.nuhExtensionFlag = val&0x01 == 1
+	return nil
+}
+
+func (v *NALUHeader) MarshalBinary() ([]byte, error) {
+	var val uint16
+	val |= uint16(v.forbiddenZeroBit&0x01) << 15
+	val |= uint16(v.naluTypePlus1&0x3f) << 9
+	val |= uint16(v.nuhTemporalID&0x07) << 6
+	val |= uint16(v.nuhReservedZero5Bits&0x1f) << 1
+	if v.nuhExtensionFlag {
+		val |= 0x01
+	}
+	return []byte{byte(val >> 8), byte(val)}, nil
+}
+
+// NALUType returns the NAL unit type, that is nal_unit_type_plus1 - 1.
+func (v *NALUHeader) NALUType() NALUType {
+	if v.naluTypePlus1 == 0 {
+		return 0
+	}
+	return NALUType(v.naluTypePlus1 - 1)
+}
+
+// @doc ISO_IEC_23094-1 MPEG-5 EVC, 7.3.1 General NAL unit syntax.
+type NALU struct {
+	*NALUHeader
+	data []byte
+}
+
+func newNALU() *NALU {
+	return &NALU{NALUHeader: newNALUHeader()}
+}
+
+// NewNALU creates an empty NALU, to be filled in by UnmarshalBinary.
+func NewNALU() *NALU {
+	return newNALU()
+}
+
+func (v *NALU) UnmarshalBinary(data []byte) error {
+	if err := v.NALUHeader.UnmarshalBinary(data); err != nil {
+		return errors.WithMessage(err, "unmarshal header")
+	}
+	v.data = data[2:]
+	return nil
+}
+
+func (v *NALU) MarshalBinary() ([]byte, error) {
+	h, err := v.NALUHeader.MarshalBinary()
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal header")
+	}
+	if len(v.data) == 0 {
+		return h, nil
+	}
+	return append(h, v.data...), nil
+}
+
+// NALUArray is one numOfArrays entry of the EVCDecoderConfigurationRecord,
+// carrying every NALU of a single NAL_unit_type, typically SPS, PPS or APS.
+type NALUArray struct {
+	ArrayCompleteness bool
+	NALUType          NALUType
+	NALUs             []*NALU
+}
+
+// EVCDecoderConfigurationRecord is the evcC box payload.
+// @doc ISOBMFF binding for MPEG-5 EVC, ISO_IEC_14496-15-2019-Amd2.pdf, EVC
+// decoder configuration record.
+type EVCDecoderConfigurationRecord struct {
+	// It is 1 for the version of this record described here.
+	configurationVersion uint8
+	profileIDC           uint8
+	levelIDC             uint8
+	toolsetIDCH          uint32
+	toolsetIDCL          uint32
+
+	chromaFormatIDC      uint8
+	bitDepthLumaMinus8   uint8
+	bitDepthChromaMinus8 uint8
+
+	picWidthInLumaSamples  uint16
+	picHeightInLumaSamples uint16
+
+	lengthSizeMinusOne uint8
+
+	arrays []NALUArray
+}
+
+func NewEVCDecoderConfigurationRecord() *EVCDecoderConfigurationRecord {
+	return &EVCDecoderConfigurationRecord{configurationVersion: 1}
+}
+
+func (v *EVCDecoderConfigurationRecord) UnmarshalBinary(data []byte) error {
+	b := data
+	if len(b) < 18 {
+		return errors.Errorf("requires 18+ only %v bytes", len(b))
+	}
+
+	v.configurationVersion = b[0]
+	v.profileIDC = b[1]
+	v.levelIDC = b[2]
+	v.toolsetIDCH = uint32(b[3])<<24 | uint32(b[4])<<16 | uint32(b[5])<<8 | uint32(b[6])
+	v.toolsetIDCL = uint32(b[7])<<24 | uint32(b[8])<<16 | uint32(b[9])<<8 | uint32(b[10])
+
+	v.chromaFormatIDC = (b[11] >> 6) & 0x03
+	v.bitDepthLumaMinus8 = (b[11] >> 3) & 0x07
+	v.bitDepthChromaMinus8 = b[11] & 0x07
+
+	v.picWidthInLumaSamples = uint16(b[12])<<8 | uint16(b[13])
+	v.picHeightInLumaSamples = uint16(b[14])<<8 | uint16(b[15])
+
+	v.lengthSizeMinusOne = b[16] & 0x03
+
+	numOfArrays := b[17]
+	b = b[18:]
+
+	for i := uint8(0); i < numOfArrays; i++ {
+		if len(b) < 3 {
+			return errors.Errorf("requires 3+ only %v bytes", len(b))
+		}
+
+		array := NALUArray{
+			ArrayCompleteness: (b[0]>>7)&0x01 == 1,
+			NALUType:          NALUType(b[0] & 0x3f),
+		}
+		numNalus := uint16(b[1])<<8 | uint16(b[2])
+		b = b[3:]
+
+		for j := uint16(0); j < numNalus; j++ {
+			if len(b) < 2 {
+				return errors.Errorf("requires 2+ only %v bytes", len(b))
+			}
+			naluLength := int(uint16(b[0])<<8 | uint16(b[1]))
+			b = b[2:]
+
+			if len(b) < naluLength {
+				return errors.Errorf("requires %v only %v bytes", naluLength, len(b))
+			}
+			nalu := newNALU()
+			if err := nalu.UnmarshalBinary(b[:naluLength]); err != nil {
+				return errors.WithMessage(err, "unmarshal nalu")
+			}
+			b = b[naluLength:]
+
+			array.NALUs = append(array.NALUs, nalu)
+		}
+
+		v.arrays = append(v.arrays, array)
+	}
+
+	return nil
+}
+
+func (v *EVCDecoderConfigurationRecord) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 18)
+
+	b[0] = v.configurationVersion
+	b[1] = v.profileIDC
+	b[2] = v.levelIDC
+	b[3], b[4], b[5], b[6] = byte(v.toolsetIDCH>>24), byte(v.toolsetIDCH>>16), byte(v.toolsetIDCH>>8), byte(v.toolsetIDCH)
+	b[7], b[8], b[9], b[10] = byte(v.toolsetIDCL>>24), byte(v.toolsetIDCL>>16), byte(v.toolsetIDCL>>8), byte(v.toolsetIDCL)
+
+	b[11] = v.chromaFormatIDC<<6 | v.bitDepthLumaMinus8<<3 | v.bitDepthChromaMinus8&0x07
+
+	b[12], b[13] = byte(v.picWidthInLumaSamples>>8), byte(v.picWidthInLumaSamples)
+	b[14], b[15] = byte(v.picHeightInLumaSamples>>8), byte(v.picHeightInLumaSamples)
+
+	b[16] = 0xfc | v.lengthSizeMinusOne&0x03
+
+	if len(v.arrays) > 0xff {
+		return nil, errors.Errorf("numOfArrays=%v overflows uint8", len(v.arrays))
+	}
+	b[17] = byte(len(v.arrays))
+
+	for _, array := range v.arrays {
+		arrayCompleteness := byte(0)
+		if array.ArrayCompleteness {
+			arrayCompleteness = 1
+		}
+		b = append(b, arrayCompleteness<<7|byte(array.NALUType)&0x3f)
+
+		if len(array.NALUs) > 0xffff {
+			return nil, errors.Errorf("numNalus=%v overflows uint16", len(array.NALUs))
+		}
+		b = append(b, byte(len(array.NALUs)>>8), byte(len(array.NALUs)))
+
+		for _, nalu := range array.NALUs {
+			nb, err := nalu.MarshalBinary()
+			if err != nil {
+				return nil, errors.WithMessage(err, "marshal nalu")
+			}
+			if len(nb) > 0xffff {
+				return nil, errors.Errorf("nalu size=%v overflows uint16", len(nb))
+			}
+			b = append(b, byte(len(nb)>>8), byte(len(nb)))
+			b = append(b, nb...)
+		}
+	}
+
+	return b, nil
+}
+
+var startCode = []byte{0x00, 0x00, 0x01}
+
+// ParseAnnexBParameterSets scans an Annex B elementary stream for start
+// codes and splits out its SPS and PPS NALUs, enough for a caller to
+// synthesize an EVCDecoderConfigurationRecord from an elementary stream.
+func ParseAnnexBParameterSets(data []byte) (sps, pps []*NALU, err error) {
+	b := data
+	for {
+		start := bytes.Index(b, startCode)
+		if start < 0 {
+			break
+		}
+		b = b[start+len(startCode):]
+
+		end := bytes.Index(b, startCode)
+		naluBytes := b
+		if end >= 0 {
+			naluBytes = b[:end]
+		}
+		for len(naluBytes) > 0 && naluBytes[len(naluBytes)-1] == 0x00 {
+			naluBytes = naluBytes[:len(naluBytes)-1]
+		}
+
+		if len(naluBytes) > 0 {
+			nalu := newNALU()
+			if err := nalu.UnmarshalBinary(naluBytes); err != nil {
+				return nil, nil, errors.WithMessage(err, "unmarshal nalu")
+			}
+			switch nalu.NALUType() {
+			case NALUTypeSPS:
+				sps = append(sps, nalu)
+			case NALUTypePPS:
+				pps = append(pps, nalu)
+			}
+		}
+
+		if end < 0 {
+			break
+		}
+		b = b[end:]
+	}
+
+	return sps, pps, nil
+}
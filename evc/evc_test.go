@@ -0,0 +1,176 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2013-2017 Oryx(ossrs)
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package evc
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNALUHeader_MarshalUnmarshal_RoundTrip(t *testing.T) {
+	cases := []*NALUHeader{
+		{forbiddenZeroBit: 0, naluTypePlus1: uint8(NALUTypeSPS) + 1, nuhTemporalID: 0, nuhReservedZero5Bits: 0, nuhExtensionFlag: false},
+		{forbiddenZeroBit: 0, naluTypePlus1: uint8(NALUTypeIDR) + 1, nuhTemporalID: 7, nuhReservedZero5Bits: 0x1f, nuhExtensionFlag: true},
+	}
+
+	for _, want := range cases {
+		b, err := want.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary(%+v) returned error: %v", want, err)
+		}
+		if len(b) != 2 {
+			t.Fatalf("MarshalBinary(%+v) = %v bytes, want 2", want, len(b))
+		}
+
+		got := newNALUHeader()
+		if err := got.UnmarshalBinary(b); err != nil {
+			t.Fatalf("UnmarshalBinary(%v) returned error: %v", b, err)
+		}
+		if *got != *want {
+			t.Errorf("round trip = %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestNALU_MarshalUnmarshal_RoundTrip(t *testing.T) {
+	nalu := newNALU()
+	nalu.naluTypePlus1 = uint8(NALUTypeIDR) + 1
+	nalu.data = []byte{0x01, 0x02, 0x03}
+
+	b, err := nalu.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary returned error: %v", err)
+	}
+
+	got := NewNALU()
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatalf("UnmarshalBinary returned error: %v", err)
+	}
+	if got.NALUType() != NALUTypeIDR || !bytes.Equal(got.data, nalu.data) {
+		t.Errorf("round trip = %v %v, want %v %v", got.NALUType(), got.data, NALUTypeIDR, nalu.data)
+	}
+}
+
+func TestEVCDecoderConfigurationRecord_MarshalUnmarshal_RoundTrip(t *testing.T) {
+	sps := newNALU()
+	sps.naluTypePlus1 = uint8(NALUTypeSPS) + 1
+	sps.data = []byte{0x01, 0x02}
+
+	pps := newNALU()
+	pps.naluTypePlus1 = uint8(NALUTypePPS) + 1
+	pps.data = []byte{0x03, 0x04, 0x05}
+
+	record := NewEVCDecoderConfigurationRecord()
+	record.profileIDC = 1
+	record.levelIDC = 30
+	record.toolsetIDCH = 0x11223344
+	record.toolsetIDCL = 0x55667788
+	record.chromaFormatIDC = 1
+	record.bitDepthLumaMinus8 = 2
+	record.bitDepthChromaMinus8 = 2
+	record.picWidthInLumaSamples = 1920
+	record.picHeightInLumaSamples = 1080
+	record.lengthSizeMinusOne = 3
+	record.arrays = []NALUArray{
+		{ArrayCompleteness: true, NALUType: NALUTypeSPS, NALUs: []*NALU{sps}},
+		{ArrayCompleteness: true, NALUType: NALUTypePPS, NALUs: []*NALU{pps}},
+	}
+
+	b, err := record.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary returned error: %v", err)
+	}
+
+	got := NewEVCDecoderConfigurationRecord()
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatalf("UnmarshalBinary returned error: %v", err)
+	}
+
+	if got.profileIDC != record.profileIDC ||
+		got.levelIDC != record.levelIDC ||
+		got.toolsetIDCH != record.toolsetIDCH ||
+		got.toolsetIDCL != record.toolsetIDCL ||
+		got.picWidthInLumaSamples != record.picWidthInLumaSamples ||
+		got.picHeightInLumaSamples != record.picHeightInLumaSamples ||
+		got.lengthSizeMinusOne != record.lengthSizeMinusOne {
+		t.Errorf("round trip fixed fields = %+v, want %+v", got, record)
+	}
+	if len(got.arrays) != 2 {
+		t.Fatalf("got %v arrays, want 2", len(got.arrays))
+	}
+	if got.arrays[0].NALUType != NALUTypeSPS || !bytes.Equal(got.arrays[0].NALUs[0].data, sps.data) {
+		t.Errorf("arrays[0] = %+v, want an SPS array carrying %v", got.arrays[0], sps.data)
+	}
+	if got.arrays[1].NALUType != NALUTypePPS || !bytes.Equal(got.arrays[1].NALUs[0].data, pps.data) {
+		t.Errorf("arrays[1] = %+v, want a PPS array carrying %v", got.arrays[1], pps.data)
+	}
+}
+
+func TestEVCDecoderConfigurationRecord_UnmarshalBinary_TooShort(t *testing.T) {
+	record := NewEVCDecoderConfigurationRecord()
+	if err := record.UnmarshalBinary(make([]byte, 17)); err == nil {
+		t.Fatal("expected error for a record shorter than the 18-byte fixed header")
+	}
+}
+
+func TestParseAnnexBParameterSets(t *testing.T) {
+	sps := newNALU()
+	sps.naluTypePlus1 = uint8(NALUTypeSPS) + 1
+	sps.data = []byte{0x01, 0x02}
+	spsBytes, err := sps.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary(sps) returned error: %v", err)
+	}
+
+	pps := newNALU()
+	pps.naluTypePlus1 = uint8(NALUTypePPS) + 1
+	pps.data = []byte{0x03}
+	ppsBytes, err := pps.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary(pps) returned error: %v", err)
+	}
+
+	idr := newNALU()
+	idr.naluTypePlus1 = uint8(NALUTypeIDR) + 1
+	idr.data = []byte{0xaa, 0xbb}
+	idrBytes, err := idr.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary(idr) returned error: %v", err)
+	}
+
+	var annexB []byte
+	for _, nalu := range [][]byte{spsBytes, ppsBytes, idrBytes} {
+		annexB = append(annexB, startCode...)
+		annexB = append(annexB, nalu...)
+	}
+
+	gotSPS, gotPPS, err := ParseAnnexBParameterSets(annexB)
+	if err != nil {
+		t.Fatalf("ParseAnnexBParameterSets returned error: %v", err)
+	}
+	if len(gotSPS) != 1 || !bytes.Equal(gotSPS[0].data, sps.data) {
+		t.Errorf("gotSPS = %v, want one SPS with data %v", gotSPS, sps.data)
+	}
+	if len(gotPPS) != 1 || !bytes.Equal(gotPPS[0].data, pps.data) {
+		t.Errorf("gotPPS = %v, want one PPS with data %v", gotPPS, pps.data)
+	}
+}
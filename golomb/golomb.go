@@ -0,0 +1,148 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2013-2017 Oryx(ossrs)
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// The oryx golomb package implements the bit-level Exp-Golomb reader used by
+// the avc package's SPS/PPS parsers, that is, the ue(v)/se(v)/u(n)/f(n)
+// descriptors of ISO_IEC_14496-10-AVC-2003.pdf at page 40, 7.2 Specification
+// of syntax functions, categories, and descriptors.
+package golomb
+
+import (
+	"github.com/ossrs/go-oryx-lib/errors"
+)
+
+// BitReader reads bits, most-significant-bit first, out of an RBSP byte
+// slice, implementing the u(n)/f(n)/ue(v)/se(v) descriptors used throughout
+// the H.264/H.265/EVC syntax tables.
+type BitReader struct {
+	data []byte
+	// The bit offset into data of the next bit to read.
+	pos int
+}
+
+// NewBitReader creates a BitReader over an already emulation-prevention
+// unescaped RBSP payload.
+func NewBitReader(data []byte) *BitReader {
+	return &BitReader{data: data}
+}
+
+// BitsLeft returns the number of bits not yet consumed.
+func (v *BitReader) BitsLeft() int {
+	return len(v.data)*8 - v.pos
+}
+
+// ReadBit reads a single bit, the f(1)/u(1) descriptor.
+func (v *BitReader) ReadBit() (uint8, error) {
+	if v.BitsLeft() < 1 {
+		return 0, errors.New("no bits left")
+	}
+
+	b := v.data[v.pos/8]
+	bit := (b >> uint(7-v.pos%8)) & 0x01
+	v.pos++
+	return bit, nil
+}
+
+// ReadFlag reads a single bit as a bool, for the *_flag fields.
+func (v *BitReader) ReadFlag() (bool, error) {
+	bit, err := v.ReadBit()
+	if err != nil {
+		return false, errors.WithMessage(err, "read bit")
+	}
+	return bit == 1, nil
+}
+
+// ReadBits reads n bits, 0 <= n <= 32, most-significant-bit first, the
+// u(n)/f(n) descriptor.
+func (v *BitReader) ReadBits(n int) (uint32, error) {
+	if n < 0 || n > 32 {
+		return 0, errors.Errorf("invalid n=%v", n)
+	}
+
+	var r uint32
+	for i := 0; i < n; i++ {
+		bit, err := v.ReadBit()
+		if err != nil {
+			return 0, errors.WithMessage(err, "read bit")
+		}
+		r = r<<1 | uint32(bit)
+	}
+	return r, nil
+}
+
+// ReadUE reads an unsigned Exp-Golomb coded value, the ue(v) descriptor.
+// @doc ISO_IEC_14496-10-AVC-2003.pdf at page 183, 9.1 Parsing process for
+// Exp-Golomb codes.
+func (v *BitReader) ReadUE() (uint32, error) {
+	leadingZeroBits := -1
+	for b := uint8(0); b == 0; leadingZeroBits++ {
+		var err error
+		if b, err = v.ReadBit(); err != nil {
+			return 0, errors.WithMessage(err, "read bit")
+		}
+	}
+
+	if leadingZeroBits == 0 {
+		return 0, nil
+	}
+
+	suffix, err := v.ReadBits(leadingZeroBits)
+	if err != nil {
+		return 0, errors.WithMessage(err, "read bits")
+	}
+
+	return (uint32(1)<<uint(leadingZeroBits) - 1) + suffix, nil
+}
+
+// MoreRBSPData reports whether any bits remain before the rbsp_trailing_bits
+// (the stop bit followed by zero padding) that terminates every RBSP, the
+// more_rbsp_data() function used to detect optional trailing syntax
+// elements. @doc ISO_IEC_14496-10-AVC-2003.pdf at page 47, 7.2 Specification
+// of syntax functions, categories, and descriptors.
+func (v *BitReader) MoreRBSPData() bool {
+	lastOne := -1
+	for i := len(v.data)*8 - 1; i >= v.pos; i-- {
+		b := (v.data[i/8] >> uint(7-i%8)) & 0x01
+		if b == 1 {
+			lastOne = i
+			break
+		}
+	}
+	if lastOne < 0 {
+		return false
+	}
+	return v.pos < lastOne
+}
+
+// ReadSE reads a signed Exp-Golomb coded value, the se(v) descriptor.
+// @doc ISO_IEC_14496-10-AVC-2003.pdf at page 184, 9.1.1 Mapping process for
+// signed Exp-Golomb codes.
+func (v *BitReader) ReadSE() (int32, error) {
+	ue, err := v.ReadUE()
+	if err != nil {
+		return 0, errors.WithMessage(err, "read ue")
+	}
+
+	if ue%2 == 0 {
+		return -int32(ue / 2), nil
+	}
+	return int32(ue+1) / 2, nil
+}
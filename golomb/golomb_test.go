@@ -0,0 +1,197 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2013-2017 Oryx(ossrs)
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package golomb
+
+import (
+	"testing"
+)
+
+func TestReadUE(t *testing.T) {
+	// Each case is the ue(v) codeword followed by the value it decodes to,
+	// per ISO_IEC_14496-10-AVC-2003.pdf at page 185, Table 9-2.
+	cases := []struct {
+		bits []byte
+		n    int
+		want uint32
+	}{
+		{[]byte{0x80}, 1, 0},       // "1"
+		{[]byte{0x40}, 3, 1},       // "010"
+		{[]byte{0x60}, 3, 2},       // "011"
+		{[]byte{0x20}, 5, 3},       // "00100"
+		{[]byte{0x28}, 5, 4},       // "00101"
+		{[]byte{0x30}, 5, 5},       // "00110"
+		{[]byte{0x38}, 5, 6},       // "00111"
+		{[]byte{0x10, 0x00}, 7, 7}, // "0001000"
+	}
+
+	for _, c := range cases {
+		r := NewBitReader(c.bits)
+		got, err := r.ReadUE()
+		if err != nil {
+			t.Fatalf("ReadUE(%v) returned error: %v", c.bits, err)
+		}
+		if got != c.want {
+			t.Errorf("ReadUE(%v) = %v, want %v", c.bits, got, c.want)
+		}
+		if r.pos != c.n {
+			t.Errorf("ReadUE(%v) consumed %v bits, want %v", c.bits, r.pos, c.n)
+		}
+	}
+}
+
+// TestReadUE_CrossesByteBoundary exercises a codeword whose leading zeros,
+// stop bit and suffix straddle a byte boundary, using marshalUE to build the
+// fixture instead of a hand-written bit pattern.
+func TestReadUE_CrossesByteBoundary(t *testing.T) {
+	for _, want := range []uint32{127, 128, 254, 255, 65534} {
+		b := marshalUE(want)
+		r := NewBitReader(b)
+		got, err := r.ReadUE()
+		if err != nil {
+			t.Fatalf("ReadUE(marshalUE(%v)) returned error: %v", want, err)
+		}
+		if got != want {
+			t.Errorf("ReadUE(marshalUE(%v)) = %v, want %v", want, got, want)
+		}
+	}
+}
+
+func TestReadUE_NoBitsLeft(t *testing.T) {
+	r := NewBitReader([]byte{0x00, 0x00})
+	if _, err := r.ReadUE(); err == nil {
+		t.Fatal("expected error reading ue(v) past the end of an all-zero buffer")
+	}
+}
+
+func TestReadSE(t *testing.T) {
+	// @doc ISO_IEC_14496-10-AVC-2003.pdf at page 45, Table 9-3, mapping of
+	// ue(v) to se(v).
+	cases := []struct {
+		ue   uint32
+		want int32
+	}{
+		{0, 0},
+		{1, 1},
+		{2, -1},
+		{3, 2},
+		{4, -2},
+		{5, 3},
+		{6, -3},
+	}
+
+	for _, c := range cases {
+		// Re-derive the ue(v) codeword for c.ue and feed it through ReadSE,
+		// rather than hand-writing each bit pattern.
+		b := marshalUE(c.ue)
+		r := NewBitReader(b)
+		got, err := r.ReadSE()
+		if err != nil {
+			t.Fatalf("ReadSE(ue=%v) returned error: %v", c.ue, err)
+		}
+		if got != c.want {
+			t.Errorf("ReadSE(ue=%v) = %v, want %v", c.ue, got, c.want)
+		}
+	}
+}
+
+// marshalUE encodes v as an ue(v) Exp-Golomb codeword, the inverse of
+// ReadUE, used only to build test fixtures.
+func marshalUE(v uint32) []byte {
+	codeNum := v + 1
+	leadingZeroBits := 0
+	for n := codeNum; n > 1; n >>= 1 {
+		leadingZeroBits++
+	}
+
+	totalBits := 2*leadingZeroBits + 1
+	b := make([]byte, (totalBits+7)/8)
+	pos := 0
+	writeBit := func(bit uint8) {
+		if bit != 0 {
+			b[pos/8] |= 1 << uint(7-pos%8)
+		}
+		pos++
+	}
+
+	for i := 0; i < leadingZeroBits; i++ {
+		writeBit(0)
+	}
+	for i := leadingZeroBits; i >= 0; i-- {
+		writeBit(uint8((codeNum >> uint(i)) & 0x01))
+	}
+
+	return b
+}
+
+func TestReadBits_CrossesByteBoundary(t *testing.T) {
+	// 0xf0, 0x0f: the middle 8 bits (starting 4 bits in) are all 0.
+	r := NewBitReader([]byte{0xf0, 0x0f})
+
+	if _, err := r.ReadBits(4); err != nil {
+		t.Fatalf("ReadBits(4) returned error: %v", err)
+	}
+
+	got, err := r.ReadBits(8)
+	if err != nil {
+		t.Fatalf("ReadBits(8) returned error: %v", err)
+	}
+	if got != 0x00 {
+		t.Errorf("ReadBits(8) across the byte boundary = %#x, want 0x00", got)
+	}
+
+	got, err = r.ReadBits(4)
+	if err != nil {
+		t.Fatalf("ReadBits(4) returned error: %v", err)
+	}
+	if got != 0x0f {
+		t.Errorf("ReadBits(4) = %#x, want 0x0f", got)
+	}
+}
+
+func TestReadBits_InvalidN(t *testing.T) {
+	r := NewBitReader([]byte{0x00})
+	if _, err := r.ReadBits(33); err == nil {
+		t.Fatal("expected error for n > 32")
+	}
+	if _, err := r.ReadBits(-1); err == nil {
+		t.Fatal("expected error for negative n")
+	}
+}
+
+func TestMoreRBSPData(t *testing.T) {
+	// rbsp_trailing_bits is a single stop bit (1) followed by zero padding.
+	r := NewBitReader([]byte{0x80})
+	if r.MoreRBSPData() {
+		t.Fatal("MoreRBSPData() = true for a buffer holding only rbsp_trailing_bits")
+	}
+
+	r = NewBitReader([]byte{0xc0}) // One data bit, then the stop bit.
+	if !r.MoreRBSPData() {
+		t.Fatal("MoreRBSPData() = false before the last data bit has been read")
+	}
+	if _, err := r.ReadBit(); err != nil {
+		t.Fatalf("ReadBit() returned error: %v", err)
+	}
+	if r.MoreRBSPData() {
+		t.Fatal("MoreRBSPData() = true once only rbsp_trailing_bits remain")
+	}
+}
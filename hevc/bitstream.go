@@ -0,0 +1,258 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2013-2017 Oryx(ossrs)
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hevc
+
+import (
+	"bytes"
+
+	"github.com/ossrs/go-oryx-lib/errors"
+)
+
+var startCode = []byte{0x00, 0x00, 0x01}
+
+// AnnexBNALUHandler receives each NALU found in an Annex B byte stream.
+type AnnexBNALUHandler func(nalu *NALU) error
+
+// AnnexBReader scans an Annex B byte stream, as used by MPEG-TS/HLS, for
+// NALUs delimited by 3-byte(0x000001) or 4-byte(0x00000001) start codes.
+// @doc ISO_IEC_23008-2-HEVC-2013.pdf at Annex B, AnnexB Byte stream Format.
+type AnnexBReader struct {
+	// The handler invoked for each complete NALU found in the stream.
+	OnNALU AnnexBNALUHandler
+	// The buffered bytes not yet consumed, including an in-progress NALU.
+	buf []byte
+	// Whether Close has already flushed the last pending NALU.
+	closed bool
+}
+
+// NewAnnexBReader creates an AnnexBReader which pushes any NALUs found in
+// Write calls to onNALU.
+func NewAnnexBReader(onNALU AnnexBNALUHandler) *AnnexBReader {
+	return &AnnexBReader{OnNALU: onNALU}
+}
+
+// Write pushes an arbitrary chunk of Annex B bytes, as they would arrive from
+// a TS demuxer, and feeds each complete NALU to OnNALU as soon as it is
+// found. A trailing partial NALU is buffered until more bytes, or Close,
+// arrive.
+func (v *AnnexBReader) Write(p []byte) (n int, err error) {
+	v.buf = append(v.buf, p...)
+
+	for {
+		start := bytes.Index(v.buf, startCode)
+		if start < 0 {
+			break
+		}
+
+		next := bytes.Index(v.buf[start+len(startCode):], startCode)
+		if next < 0 {
+			// Only one start code buffered so far, wait for the next one (or
+			// Close) to know where this NALU ends.
+			break
+		}
+		next += start + len(startCode)
+
+		if err := v.emit(v.buf[start+len(startCode) : next]); err != nil {
+			return len(p), errors.WithMessage(err, "emit")
+		}
+
+		v.buf = v.buf[next:]
+	}
+
+	return len(p), nil
+}
+
+// Close flushes the last buffered NALU, if any, to OnNALU.
+func (v *AnnexBReader) Close() error {
+	if v.closed {
+		return nil
+	}
+	v.closed = true
+
+	start := bytes.Index(v.buf, startCode)
+	if start < 0 {
+		return nil
+	}
+
+	if err := v.emit(v.buf[start+len(startCode):]); err != nil {
+		return errors.WithMessage(err, "emit")
+	}
+	v.buf = nil
+	return nil
+}
+
+// emit strips every trailing zero byte (such as the extra leading zero of a
+// following 4-byte start code) and dispatches the NALU.
+func (v *AnnexBReader) emit(b []byte) error {
+	for len(b) > 0 && b[len(b)-1] == 0x00 {
+		b = b[:len(b)-1]
+	}
+	if len(b) == 0 {
+		return nil
+	}
+
+	nalu := newNALU()
+	if err := nalu.UnmarshalBinary(b); err != nil {
+		return errors.WithMessage(err, "unmarshal nalu")
+	}
+	return v.OnNALU(nalu)
+}
+
+// UnmarshalAnnexB parses a complete, in-memory Annex B byte stream into its
+// NALUs. For incrementally arriving bytes, use AnnexBReader instead.
+func UnmarshalAnnexB(data []byte) ([]*NALU, error) {
+	var nalus []*NALU
+	r := NewAnnexBReader(func(nalu *NALU) error {
+		nalus = append(nalus, nalu)
+		return nil
+	})
+	if _, err := r.Write(data); err != nil {
+		return nil, errors.WithMessage(err, "write")
+	}
+	if err := r.Close(); err != nil {
+		return nil, errors.WithMessage(err, "close")
+	}
+	return nalus, nil
+}
+
+// MarshalAnnexB writes nalus as an Annex B byte stream, prefixing each one
+// with a 4-byte start code.
+func MarshalAnnexB(nalus ...*NALU) ([]byte, error) {
+	var r []byte
+	for _, nalu := range nalus {
+		b, err := nalu.MarshalBinary()
+		if err != nil {
+			return nil, errors.WithMessage(err, "marshal nalu")
+		}
+		r = append(r, 0x00, 0x00, 0x00, 0x01)
+		r = append(r, b...)
+	}
+	return r, nil
+}
+
+// SplitNALUs walks an IBMF sample, as found in an MP4 "mdat", which is a
+// sequence of NALUs each prefixed with a NALUnitLength field of
+// v.lengthSizeMinusOne+1 bytes, and slices out the NALUs it contains.
+func (v *HEVCDecoderConfigurationRecord) SplitNALUs(sample []byte) ([]*NALU, error) {
+	lengthSize := int(v.lengthSizeMinusOne) + 1
+	if lengthSize != 1 && lengthSize != 2 && lengthSize != 4 {
+		return nil, errors.Errorf("invalid lengthSize=%v", lengthSize)
+	}
+
+	var nalus []*NALU
+	b := sample
+	for len(b) > 0 {
+		if len(b) < lengthSize {
+			return nil, errors.Errorf("requires %v only %v bytes", lengthSize, len(b))
+		}
+
+		var length int
+		for i := 0; i < lengthSize; i++ {
+			length = length<<8 | int(b[i])
+		}
+		b = b[lengthSize:]
+
+		if len(b) < length {
+			return nil, errors.Errorf("requires %v only %v bytes", length, len(b))
+		}
+
+		nalu := newNALU()
+		if err := nalu.UnmarshalBinary(b[:length]); err != nil {
+			return nil, errors.WithMessage(err, "unmarshal nalu")
+		}
+		b = b[length:]
+
+		nalus = append(nalus, nalu)
+	}
+
+	return nalus, nil
+}
+
+// PackNALUs is the inverse of SplitNALUs, prefixing each NALU with a
+// NALUnitLength field of v.lengthSizeMinusOne+1 bytes to build an IBMF
+// sample.
+func (v *HEVCDecoderConfigurationRecord) PackNALUs(nalus []*NALU) ([]byte, error) {
+	lengthSize := int(v.lengthSizeMinusOne) + 1
+	if lengthSize != 1 && lengthSize != 2 && lengthSize != 4 {
+		return nil, errors.Errorf("invalid lengthSize=%v", lengthSize)
+	}
+
+	var sample []byte
+	for _, nalu := range nalus {
+		b, err := nalu.MarshalBinary()
+		if err != nil {
+			return nil, errors.WithMessage(err, "marshal nalu")
+		}
+
+		if lengthSize < 4 && len(b) >= 1<<uint(8*lengthSize) {
+			return nil, errors.Errorf("nalu size=%v overflows lengthSize=%v", len(b), lengthSize)
+		}
+
+		length := make([]byte, lengthSize)
+		for i := 0; i < lengthSize; i++ {
+			length[lengthSize-1-i] = byte(len(b) >> uint(8*i))
+		}
+
+		sample = append(sample, length...)
+		sample = append(sample, b...)
+	}
+
+	return sample, nil
+}
+
+// AnnexBToIBMF remuxes an Annex B elementary stream into an IBMF sample with
+// lengthSize-byte (1, 2 or 4) NALUnitLength fields, as required when writing
+// the NALUs into an MP4 "mdat".
+func AnnexBToIBMF(annexB []byte, lengthSize int) ([]byte, error) {
+	if lengthSize != 1 && lengthSize != 2 && lengthSize != 4 {
+		return nil, errors.Errorf("invalid lengthSize=%v", lengthSize)
+	}
+
+	nalus, err := UnmarshalAnnexB(annexB)
+	if err != nil {
+		return nil, errors.WithMessage(err, "unmarshal annexb")
+	}
+
+	record := NewHEVCDecoderConfigurationRecord()
+	record.lengthSizeMinusOne = uint8(lengthSize - 1)
+
+	return record.PackNALUs(nalus)
+}
+
+// IBMFToAnnexB remuxes an IBMF sample with lengthSize-byte (1, 2 or 4)
+// NALUnitLength fields back into an Annex B elementary stream, as required
+// when feeding the NALUs to an MPEG-TS/HLS muxer.
+func IBMFToAnnexB(sample []byte, lengthSize int) ([]byte, error) {
+	if lengthSize != 1 && lengthSize != 2 && lengthSize != 4 {
+		return nil, errors.Errorf("invalid lengthSize=%v", lengthSize)
+	}
+
+	record := NewHEVCDecoderConfigurationRecord()
+	record.lengthSizeMinusOne = uint8(lengthSize - 1)
+
+	nalus, err := record.SplitNALUs(sample)
+	if err != nil {
+		return nil, errors.WithMessage(err, "split nalus")
+	}
+
+	return MarshalAnnexB(nalus...)
+}
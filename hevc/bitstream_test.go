@@ -0,0 +1,156 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2013-2017 Oryx(ossrs)
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hevc
+
+import (
+	"bytes"
+	"testing"
+)
+
+func newTestNALU(naluType NALUType, data []byte) *NALU {
+	nalu := newNALU()
+	nalu.naluType = naluType
+	nalu.nuhTemporalIDPlus1 = 1
+	nalu.data = data
+	return nalu
+}
+
+func TestMarshalUnmarshalAnnexB(t *testing.T) {
+	vps := newTestNALU(NALUTypeVPS, []byte{0x01, 0x02})
+	idr := newTestNALU(NALUTypeIDRWRADL, []byte{0xaa, 0x00, 0x00, 0x03})
+
+	b, err := MarshalAnnexB(vps, idr)
+	if err != nil {
+		t.Fatalf("MarshalAnnexB returned error: %v", err)
+	}
+
+	nalus, err := UnmarshalAnnexB(b)
+	if err != nil {
+		t.Fatalf("UnmarshalAnnexB returned error: %v", err)
+	}
+	if len(nalus) != 2 {
+		t.Fatalf("got %v NALUs, want 2", len(nalus))
+	}
+	if nalus[0].naluType != NALUTypeVPS || !bytes.Equal(nalus[0].data, vps.data) {
+		t.Errorf("nalus[0] = %v %v, want VPS %v", nalus[0].naluType, nalus[0].data, vps.data)
+	}
+	if nalus[1].naluType != NALUTypeIDRWRADL || !bytes.Equal(nalus[1].data, idr.data) {
+		t.Errorf("nalus[1] = %v %v, want IDR_W_RADL %v", nalus[1].naluType, nalus[1].data, idr.data)
+	}
+}
+
+func TestAnnexBReader_StreamedAcrossWrites(t *testing.T) {
+	vps := newTestNALU(NALUTypeVPS, []byte{0x01, 0x02, 0x03})
+	sps := newTestNALU(NALUTypeSPS, []byte{0xaa, 0xbb})
+
+	b, err := MarshalAnnexB(vps, sps)
+	if err != nil {
+		t.Fatalf("MarshalAnnexB returned error: %v", err)
+	}
+
+	var got []*NALU
+	r := NewAnnexBReader(func(nalu *NALU) error {
+		got = append(got, nalu)
+		return nil
+	})
+
+	for i := range b {
+		if _, err := r.Write(b[i : i+1]); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("got %v NALUs, want 2", len(got))
+	}
+	if got[0].naluType != NALUTypeVPS || !bytes.Equal(got[0].data, vps.data) {
+		t.Errorf("got[0] = %v %v, want VPS %v", got[0].naluType, got[0].data, vps.data)
+	}
+	if got[1].naluType != NALUTypeSPS || !bytes.Equal(got[1].data, sps.data) {
+		t.Errorf("got[1] = %v %v, want SPS %v", got[1].naluType, got[1].data, sps.data)
+	}
+}
+
+func TestSplitPackNALUs_RoundTrip(t *testing.T) {
+	record := NewHEVCDecoderConfigurationRecord()
+	record.lengthSizeMinusOne = 3 // 4-byte NALUnitLength.
+
+	nalus := []*NALU{
+		newTestNALU(NALUTypeVPS, []byte{0x01}),
+		newTestNALU(NALUTypeIDRNLP, []byte{0xaa, 0xbb, 0xcc}),
+	}
+
+	sample, err := record.PackNALUs(nalus)
+	if err != nil {
+		t.Fatalf("PackNALUs returned error: %v", err)
+	}
+
+	got, err := record.SplitNALUs(sample)
+	if err != nil {
+		t.Fatalf("SplitNALUs returned error: %v", err)
+	}
+	if len(got) != len(nalus) {
+		t.Fatalf("got %v NALUs, want %v", len(got), len(nalus))
+	}
+	for i := range nalus {
+		if got[i].naluType != nalus[i].naluType || !bytes.Equal(got[i].data, nalus[i].data) {
+			t.Errorf("nalus[%v] = %v %v, want %v %v", i, got[i].naluType, got[i].data, nalus[i].naluType, nalus[i].data)
+		}
+	}
+}
+
+func TestAnnexBToIBMFToAnnexB_RoundTrip(t *testing.T) {
+	vps := newTestNALU(NALUTypeVPS, []byte{0x01, 0x02})
+	idr := newTestNALU(NALUTypeIDRNLP, []byte{0xaa, 0xbb, 0x00, 0x00, 0x03})
+
+	annexB, err := MarshalAnnexB(vps, idr)
+	if err != nil {
+		t.Fatalf("MarshalAnnexB returned error: %v", err)
+	}
+
+	ibmf, err := AnnexBToIBMF(annexB, 4)
+	if err != nil {
+		t.Fatalf("AnnexBToIBMF returned error: %v", err)
+	}
+
+	back, err := IBMFToAnnexB(ibmf, 4)
+	if err != nil {
+		t.Fatalf("IBMFToAnnexB returned error: %v", err)
+	}
+
+	nalus, err := UnmarshalAnnexB(back)
+	if err != nil {
+		t.Fatalf("UnmarshalAnnexB returned error: %v", err)
+	}
+	if len(nalus) != 2 {
+		t.Fatalf("got %v NALUs, want 2", len(nalus))
+	}
+	if nalus[0].naluType != NALUTypeVPS || !bytes.Equal(nalus[0].data, vps.data) {
+		t.Errorf("nalus[0] = %v %v, want VPS %v", nalus[0].naluType, nalus[0].data, vps.data)
+	}
+	if nalus[1].naluType != NALUTypeIDRNLP || !bytes.Equal(nalus[1].data, idr.data) {
+		t.Errorf("nalus[1] = %v %v, want IDR_N_LP %v", nalus[1].naluType, nalus[1].data, idr.data)
+	}
+}
@@ -0,0 +1,310 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2013-2017 Oryx(ossrs)
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// The oryx HEVC package includes some utilites for H.265/HEVC, the peer of
+// the avc package for H.264/AVC.
+// The NALU(Network Abstraction Layer Unit) is suitable for transmission over
+// network. We could package NALUs by AnnexB or IBMF according to different
+// scenarios.
+// 	@note AnnexB is designed for bit-oriented stream, such as MPEG-TS/HLS, please
+// 		read ISO_IEC_23008-2-HEVC-2013.pdf at Annex B, AnnexB Byte stream Format.
+//	@note IBMF is designed for file storage, such as MP4, please read
+//		ISO_IEC_14496-15-2017.pdf at §8, HEVC decoder configuration record.
+package hevc
+
+import (
+	"github.com/ossrs/go-oryx-lib/errors"
+)
+
+// NALUType is the 6-bit nal_unit_type of the 2-byte HEVC NAL unit header.
+// @doc ISO_IEC_23008-2-HEVC-2013.pdf at page 23, Table 7-1 NAL unit type
+// codes and NAL unit type classes.
+type NALUType uint8
+
+const (
+	NALUTypeIDRWRADL NALUType = 19
+	NALUTypeIDRNLP   NALUType = 20
+	NALUTypeCRA      NALUType = 21
+
+	NALUTypeVPS       NALUType = 32
+	NALUTypeSPS       NALUType = 33
+	NALUTypePPS       NALUType = 34
+	NALUTypeAUD       NALUType = 35
+	NALUTypePrefixSEI NALUType = 39
+	NALUTypeSuffixSEI NALUType = 40
+)
+
+// @doc ISO_IEC_23008-2-HEVC-2013.pdf at page 20, 7.3.1.2 NAL unit header
+// syntax.
+type NALUHeader struct {
+	// The 1-bit forbidden_zero_bit, always 0.
+	forbiddenZeroBit uint8
+	// The 6-bit nal_unit_type.
+	naluType NALUType
+	// The 6-bit nuh_layer_id.
+	nuhLayerID uint8
+	// The 3-bit nuh_temporal_id_plus1.
+	nuhTemporalIDPlus1 uint8
+}
+
+func newNALUHeader() *NALUHeader {
+	return &NALUHeader{}
+}
+
+func (v *NALUHeader) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 {
+		return errors.Errorf("requires 2+ only %v bytes", len(data))
+	}
+
+	v.forbiddenZeroBit = (data[0] >> 7) & 0x01
+	v.naluType = NALUType((data[0] >> 1) & 0x3f)
+	v.nuhLayerID = (data[0]&0x01)<<5 | (data[1]>>3)&0x1f
+	v.nuhTemporalIDPlus1 = data[1] & 0x07
+	return nil
+}
+
+func (v *NALUHeader) MarshalBinary() ([]byte, error) {
+	b0 := v.forbiddenZeroBit<<7 | byte(v.naluType)<<1 | (v.nuhLayerID>>5)&0x01
+	b1 := (v.nuhLayerID&0x1f)<<3 | v.nuhTemporalIDPlus1&0x07
+	return []byte{b0, b1}, nil
+}
+
+// @doc ISO_IEC_23008-2-HEVC-2013.pdf at page 20, 7.3.1.1 General NAL unit
+// syntax.
+type NALU struct {
+	*NALUHeader
+	data []byte
+}
+
+func newNALU() *NALU {
+	return &NALU{NALUHeader: newNALUHeader()}
+}
+
+// NewNALU creates an empty NALU, to be filled in by UnmarshalBinary.
+func NewNALU() *NALU {
+	return newNALU()
+}
+
+func (v *NALU) UnmarshalBinary(data []byte) error {
+	if err := v.NALUHeader.UnmarshalBinary(data); err != nil {
+		return errors.WithMessage(err, "unmarshal header")
+	}
+	v.data = data[2:]
+	return nil
+}
+
+func (v *NALU) MarshalBinary() ([]byte, error) {
+	h, err := v.NALUHeader.MarshalBinary()
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal header")
+	}
+	if len(v.data) == 0 {
+		return h, nil
+	}
+	return append(h, v.data...), nil
+}
+
+// NALUArray is one numOfArrays entry of the HEVCDecoderConfigurationRecord,
+// carrying every NALU of a single NAL_unit_type, typically VPS, SPS or PPS.
+type NALUArray struct {
+	// Whether all NALUs of this type are in this array, and none are in the
+	// stream, for the whole parameter set coded video sequence.
+	ArrayCompleteness bool
+	NALUType          NALUType
+	NALUs             []*NALU
+}
+
+// HEVCDecoderConfigurationRecord is the hvcC box payload.
+// @doc ISO_IEC_14496-15-2017.pdf at §8.3.3.1, HEVCDecoderConfigurationRecord.
+type HEVCDecoderConfigurationRecord struct {
+	configurationVersion uint8
+
+	generalProfileSpace uint8
+	generalTierFlag     bool
+	generalProfileIDC   uint8
+
+	generalProfileCompatibilityFlags uint32
+	// 48-bit general_constraint_indicator_flags.
+	generalConstraintIndicatorFlags uint64
+	generalLevelIDC                 uint8
+
+	minSpatialSegmentationIDC uint16
+	parallelismType           uint8
+	chromaFormat              uint8
+	bitDepthLumaMinus8        uint8
+	bitDepthChromaMinus8      uint8
+	avgFrameRate              uint16
+
+	constantFrameRate  uint8
+	numTemporalLayers  uint8
+	temporalIDNested   bool
+	lengthSizeMinusOne uint8
+
+	arrays []NALUArray
+}
+
+func NewHEVCDecoderConfigurationRecord() *HEVCDecoderConfigurationRecord {
+	return &HEVCDecoderConfigurationRecord{}
+}
+
+func (v *HEVCDecoderConfigurationRecord) UnmarshalBinary(data []byte) error {
+	b := data
+	if len(b) < 23 {
+		return errors.Errorf("requires 23+ only %v bytes", len(b))
+	}
+
+	v.configurationVersion = b[0]
+
+	v.generalProfileSpace = (b[1] >> 6) & 0x03
+	v.generalTierFlag = (b[1]>>5)&0x01 == 1
+	v.generalProfileIDC = b[1] & 0x1f
+
+	v.generalProfileCompatibilityFlags = uint32(b[2])<<24 | uint32(b[3])<<16 | uint32(b[4])<<8 | uint32(b[5])
+
+	var constraintFlags uint64
+	for i := 0; i < 6; i++ {
+		constraintFlags = constraintFlags<<8 | uint64(b[6+i])
+	}
+	v.generalConstraintIndicatorFlags = constraintFlags
+
+	v.generalLevelIDC = b[12]
+
+	v.minSpatialSegmentationIDC = uint16(b[13]&0x0f)<<8 | uint16(b[14])
+	v.parallelismType = b[15] & 0x03
+	v.chromaFormat = b[16] & 0x03
+	v.bitDepthLumaMinus8 = b[17] & 0x07
+	v.bitDepthChromaMinus8 = b[18] & 0x07
+	v.avgFrameRate = uint16(b[19])<<8 | uint16(b[20])
+
+	v.constantFrameRate = (b[21] >> 6) & 0x03
+	v.numTemporalLayers = (b[21] >> 3) & 0x07
+	v.temporalIDNested = (b[21]>>2)&0x01 == 1
+	v.lengthSizeMinusOne = b[21] & 0x03
+
+	numOfArrays := b[22]
+	b = b[23:]
+
+	for i := uint8(0); i < numOfArrays; i++ {
+		if len(b) < 3 {
+			return errors.Errorf("requires 3+ only %v bytes", len(b))
+		}
+
+		array := NALUArray{
+			ArrayCompleteness: (b[0]>>7)&0x01 == 1,
+			NALUType:          NALUType(b[0] & 0x3f),
+		}
+		numNalus := uint16(b[1])<<8 | uint16(b[2])
+		b = b[3:]
+
+		for j := uint16(0); j < numNalus; j++ {
+			if len(b) < 2 {
+				return errors.Errorf("requires 2+ only %v bytes", len(b))
+			}
+			naluLength := int(uint16(b[0])<<8 | uint16(b[1]))
+			b = b[2:]
+
+			if len(b) < naluLength {
+				return errors.Errorf("requires %v only %v bytes", naluLength, len(b))
+			}
+			nalu := newNALU()
+			if err := nalu.UnmarshalBinary(b[:naluLength]); err != nil {
+				return errors.WithMessage(err, "unmarshal nalu")
+			}
+			b = b[naluLength:]
+
+			array.NALUs = append(array.NALUs, nalu)
+		}
+
+		v.arrays = append(v.arrays, array)
+	}
+
+	return nil
+}
+
+func (v *HEVCDecoderConfigurationRecord) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 23)
+
+	b[0] = v.configurationVersion
+
+	tierFlag := byte(0)
+	if v.generalTierFlag {
+		tierFlag = 1
+	}
+	b[1] = v.generalProfileSpace<<6 | tierFlag<<5 | v.generalProfileIDC&0x1f
+
+	b[2] = byte(v.generalProfileCompatibilityFlags >> 24)
+	b[3] = byte(v.generalProfileCompatibilityFlags >> 16)
+	b[4] = byte(v.generalProfileCompatibilityFlags >> 8)
+	b[5] = byte(v.generalProfileCompatibilityFlags)
+
+	for i := 0; i < 6; i++ {
+		b[6+i] = byte(v.generalConstraintIndicatorFlags >> uint(8*(5-i)))
+	}
+
+	b[12] = v.generalLevelIDC
+
+	b[13] = 0xf0 | byte(v.minSpatialSegmentationIDC>>8)
+	b[14] = byte(v.minSpatialSegmentationIDC)
+	b[15] = 0xfc | v.parallelismType&0x03
+	b[16] = 0xfc | v.chromaFormat&0x03
+	b[17] = 0xf8 | v.bitDepthLumaMinus8&0x07
+	b[18] = 0xf8 | v.bitDepthChromaMinus8&0x07
+	b[19] = byte(v.avgFrameRate >> 8)
+	b[20] = byte(v.avgFrameRate)
+
+	temporalIDNested := byte(0)
+	if v.temporalIDNested {
+		temporalIDNested = 1
+	}
+	b[21] = v.constantFrameRate<<6 | v.numTemporalLayers<<3 | temporalIDNested<<2 | v.lengthSizeMinusOne&0x03
+
+	if len(v.arrays) > 0xff {
+		return nil, errors.Errorf("numOfArrays=%v overflows uint8", len(v.arrays))
+	}
+	b[22] = byte(len(v.arrays))
+
+	for _, array := range v.arrays {
+		arrayCompleteness := byte(0)
+		if array.ArrayCompleteness {
+			arrayCompleteness = 1
+		}
+		b = append(b, arrayCompleteness<<7|byte(array.NALUType)&0x3f)
+
+		if len(array.NALUs) > 0xffff {
+			return nil, errors.Errorf("numNalus=%v overflows uint16", len(array.NALUs))
+		}
+		b = append(b, byte(len(array.NALUs)>>8), byte(len(array.NALUs)))
+
+		for _, nalu := range array.NALUs {
+			nb, err := nalu.MarshalBinary()
+			if err != nil {
+				return nil, errors.WithMessage(err, "marshal nalu")
+			}
+			if len(nb) > 0xffff {
+				return nil, errors.Errorf("nalu size=%v overflows uint16", len(nb))
+			}
+			b = append(b, byte(len(nb)>>8), byte(len(nb)))
+			b = append(b, nb...)
+		}
+	}
+
+	return b, nil
+}
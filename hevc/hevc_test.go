@@ -0,0 +1,135 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2013-2017 Oryx(ossrs)
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hevc
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNALUHeader_MarshalUnmarshal_RoundTrip(t *testing.T) {
+	cases := []*NALUHeader{
+		{forbiddenZeroBit: 0, naluType: NALUTypeVPS, nuhLayerID: 0, nuhTemporalIDPlus1: 1},
+		{forbiddenZeroBit: 0, naluType: NALUTypeIDRWRADL, nuhLayerID: 0x3f, nuhTemporalIDPlus1: 7},
+		{forbiddenZeroBit: 0, naluType: NALUTypeSuffixSEI, nuhLayerID: 0x15, nuhTemporalIDPlus1: 3},
+	}
+
+	for _, want := range cases {
+		b, err := want.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary(%+v) returned error: %v", want, err)
+		}
+		if len(b) != 2 {
+			t.Fatalf("MarshalBinary(%+v) = %v bytes, want 2", want, len(b))
+		}
+
+		got := newNALUHeader()
+		if err := got.UnmarshalBinary(b); err != nil {
+			t.Fatalf("UnmarshalBinary(%v) returned error: %v", b, err)
+		}
+		if *got != *want {
+			t.Errorf("round trip = %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestNALU_MarshalUnmarshal_RoundTrip(t *testing.T) {
+	nalu := newNALU()
+	nalu.naluType = NALUTypeIDRNLP
+	nalu.nuhTemporalIDPlus1 = 1
+	nalu.data = []byte{0x01, 0x02, 0x03}
+
+	b, err := nalu.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary returned error: %v", err)
+	}
+
+	got := NewNALU()
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatalf("UnmarshalBinary returned error: %v", err)
+	}
+	if got.naluType != nalu.naluType || !bytes.Equal(got.data, nalu.data) {
+		t.Errorf("round trip = %v %v, want %v %v", got.naluType, got.data, nalu.naluType, nalu.data)
+	}
+}
+
+func TestHEVCDecoderConfigurationRecord_MarshalUnmarshal_RoundTrip(t *testing.T) {
+	vps := newNALU()
+	vps.naluType = NALUTypeVPS
+	vps.data = []byte{0x01, 0x02}
+
+	sps := newNALU()
+	sps.naluType = NALUTypeSPS
+	sps.data = []byte{0x03, 0x04, 0x05}
+
+	record := NewHEVCDecoderConfigurationRecord()
+	record.configurationVersion = 1
+	record.generalProfileSpace = 0
+	record.generalTierFlag = true
+	record.generalProfileIDC = 1
+	record.generalProfileCompatibilityFlags = 0x60000000
+	record.generalConstraintIndicatorFlags = 0x112233445566
+	record.generalLevelIDC = 120
+	record.minSpatialSegmentationIDC = 0
+	record.parallelismType = 0
+	record.chromaFormat = 1
+	record.bitDepthLumaMinus8 = 0
+	record.bitDepthChromaMinus8 = 0
+	record.lengthSizeMinusOne = 3
+	record.arrays = []NALUArray{
+		{ArrayCompleteness: true, NALUType: NALUTypeVPS, NALUs: []*NALU{vps}},
+		{ArrayCompleteness: true, NALUType: NALUTypeSPS, NALUs: []*NALU{sps}},
+	}
+
+	b, err := record.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary returned error: %v", err)
+	}
+
+	got := NewHEVCDecoderConfigurationRecord()
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatalf("UnmarshalBinary returned error: %v", err)
+	}
+
+	if got.generalTierFlag != record.generalTierFlag ||
+		got.generalProfileIDC != record.generalProfileIDC ||
+		got.generalLevelIDC != record.generalLevelIDC ||
+		got.lengthSizeMinusOne != record.lengthSizeMinusOne {
+		t.Errorf("round trip fixed fields = %+v, want %+v", got, record)
+	}
+	if len(got.arrays) != 2 {
+		t.Fatalf("got %v arrays, want 2", len(got.arrays))
+	}
+	if got.arrays[0].NALUType != NALUTypeVPS || !bytes.Equal(got.arrays[0].NALUs[0].data, vps.data) {
+		t.Errorf("arrays[0] = %+v, want a VPS array carrying %v", got.arrays[0], vps.data)
+	}
+	if got.arrays[1].NALUType != NALUTypeSPS || !bytes.Equal(got.arrays[1].NALUs[0].data, sps.data) {
+		t.Errorf("arrays[1] = %+v, want an SPS array carrying %v", got.arrays[1], sps.data)
+	}
+}
+
+func TestHEVCDecoderConfigurationRecord_UnmarshalBinary_TooShort(t *testing.T) {
+	record := NewHEVCDecoderConfigurationRecord()
+	if err := record.UnmarshalBinary(make([]byte, 22)); err == nil {
+		t.Fatal("expected error for a record shorter than the 23-byte fixed header")
+	}
+}